@@ -1,3 +1,4 @@
+//go:build ignore
 // +build ignore
 
 package main
@@ -6,7 +7,6 @@ import (
 	"flag"
 	"fmt"
 	"github.com/PineiroHosting/deeplgobindings/pkg"
-	"net/http"
 )
 
 func main() {
@@ -17,9 +17,10 @@ func main() {
 		flag.PrintDefaults()
 		return
 	}
-	client := &deeplclient.Client{
-		AuthKey: []byte(*authKey),
-		Client:  &http.Client{},
+	// the endpoint is chosen depending on the type of the auth key
+	client, err := deeplclient.NewClient(*authKey)
+	if err != nil {
+		panic(err)
 	}
 	resp, err := client.GetUsage()
 	// basic error handling because it is an example
@@ -27,6 +28,18 @@ func main() {
 		panic(err)
 	}
 	fmt.Println("===================================================")
-	fmt.Printf("| Monthly usage: %32s |\n", fmt.Sprintf("%f%s (%d/%d)", float64(resp.CharacterCount) / float64(resp.CharacterLimit) / 100.0, "%", resp.CharacterCount, resp.CharacterLimit))
+	if resp.HasCharacterLimit() {
+		fmt.Printf("| Monthly usage: %32s |\n", fmt.Sprintf("%.2f%% (%d/%d)", resp.PercentUsed(),
+			resp.CharacterCount, resp.CharacterLimit))
+	} else {
+		fmt.Printf("| Monthly usage: %32s |\n", fmt.Sprintf("%d (unlimited)", resp.CharacterCount))
+	}
+	if resp.HasDocumentLimit() {
+		fmt.Printf("| Documents: %36s |\n", fmt.Sprintf("%d/%d", resp.DocumentCount, resp.DocumentLimit))
+	}
+	if resp.HasTeamDocumentLimit() {
+		fmt.Printf("| Team documents: %31s |\n", fmt.Sprintf("%d/%d", resp.TeamDocumentCount,
+			resp.TeamDocumentLimit))
+	}
 	fmt.Println("===================================================")
 }
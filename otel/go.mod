@@ -0,0 +1,22 @@
+module github.com/PineiroHosting/deeplgobindings/otel
+
+go 1.20
+
+require (
+	github.com/PineiroHosting/deeplgobindings v0.0.0-20261017000700-4522c67ce796
+	go.opentelemetry.io/otel v1.24.0
+	go.opentelemetry.io/otel/sdk v1.24.0
+	go.opentelemetry.io/otel/trace v1.24.0
+)
+
+require (
+	github.com/go-logr/logr v1.4.1 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
+	go.opentelemetry.io/otel/metric v1.24.0 // indirect
+	golang.org/x/sys v0.17.0 // indirect
+)
+
+// The replace directive builds the module against the root module of this repository during development and is ignored
+// by dependents, which use the required version. The required version must be updated whenever the module starts to
+// depend on newer changes of the root module.
+replace github.com/PineiroHosting/deeplgobindings => ../
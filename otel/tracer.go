@@ -0,0 +1,101 @@
+// Package deeplotel provides an OpenTelemetry adapter for the tracer interface of the DeepL client. It is a separate
+// module, so the client itself stays free of dependencies.
+package deeplotel
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	deeplclient "github.com/PineiroHosting/deeplgobindings/pkg"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName is the name of the tracer obtained from the tracer provider.
+const instrumentationName = "github.com/PineiroHosting/deeplgobindings"
+
+// httpRequestSpanName is the name of the spans started by the client for single HTTP requests.
+const httpRequestSpanName = "deepl.http_request"
+
+// Tracer starts OpenTelemetry spans for the operations of a client and propagates the trace context to the API
+// server. It implements deeplclient.Tracer and deeplclient.TracePropagator and is safe for concurrent use.
+type Tracer struct {
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+}
+
+// NewTracer creates a new tracer starting spans with the given tracer provider and propagating the trace context with
+// the given propagator (e.g. propagation.TraceContext for W3C trace context headers). If nil, the global tracer
+// provider or propagator of OpenTelemetry is used.
+func NewTracer(provider trace.TracerProvider, propagator propagation.TextMapPropagator) *Tracer {
+	if provider == nil {
+		provider = otel.GetTracerProvider()
+	}
+	if propagator == nil {
+		propagator = otel.GetTextMapPropagator()
+	}
+	return &Tracer{
+		tracer:     provider.Tracer(instrumentationName),
+		propagator: propagator,
+	}
+}
+
+// Start starts a new span as child of the span contained in the context, if any. The spans of HTTP requests have the
+// client kind, all other spans are internal.
+func (tracer *Tracer) Start(ctx context.Context, name string) (context.Context, deeplclient.Span) {
+	kind := trace.SpanKindInternal
+	if name == httpRequestSpanName {
+		kind = trace.SpanKindClient
+	}
+	ctx, span := tracer.tracer.Start(ctx, name, trace.WithSpanKind(kind))
+	return ctx, &otelSpan{span}
+}
+
+// Inject adds the trace context of the span contained in the context to the header.
+func (tracer *Tracer) Inject(ctx context.Context, header http.Header) {
+	tracer.propagator.Inject(ctx, propagation.HeaderCarrier(header))
+}
+
+// otelSpan adapts an OpenTelemetry span to deeplclient.Span.
+type otelSpan struct {
+	span trace.Span
+}
+
+// SetAttribute converts the value into an OpenTelemetry attribute and sets it on the span. Values of unsupported
+// types are formatted as strings.
+func (span *otelSpan) SetAttribute(key string, value interface{}) {
+	span.span.SetAttributes(attributeOf(key, value))
+}
+
+// RecordError records the error and marks the span as failed.
+func (span *otelSpan) RecordError(err error) {
+	span.span.RecordError(err)
+	span.span.SetStatus(codes.Error, err.Error())
+}
+
+// End finishes the span.
+func (span *otelSpan) End() {
+	span.span.End()
+}
+
+// attributeOf converts the attribute set by the client into an OpenTelemetry attribute.
+func attributeOf(key string, value interface{}) attribute.KeyValue {
+	switch value := value.(type) {
+	case string:
+		return attribute.String(key, value)
+	case int:
+		return attribute.Int(key, value)
+	case int64:
+		return attribute.Int64(key, value)
+	case bool:
+		return attribute.Bool(key, value)
+	case float64:
+		return attribute.Float64(key, value)
+	default:
+		return attribute.String(key, fmt.Sprint(value))
+	}
+}
@@ -0,0 +1,98 @@
+package deeplotel
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	deeplclient "github.com/PineiroHosting/deeplgobindings/pkg"
+	"github.com/PineiroHosting/deeplgobindings/pkg/deepltest"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// newTracedClient creates a client for a new fake server whose spans are recorded.
+func newTracedClient(t *testing.T) (*deeplclient.Client, *deepltest.Server, *tracetest.SpanRecorder) {
+	server := deepltest.NewServer()
+	t.Cleanup(server.Close)
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	client, err := deeplclient.NewClient("test",
+		deeplclient.WithEndpoint(server.EndpointUrl()),
+		deeplclient.WithHTTPClient(server.Client()),
+		deeplclient.WithTracer(NewTracer(provider, propagation.TraceContext{})),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client, server, recorder
+}
+
+// findSpan returns the ended span with the given name.
+func findSpan(t *testing.T, recorder *tracetest.SpanRecorder, name string) sdktrace.ReadOnlySpan {
+	for _, span := range recorder.Ended() {
+		if span.Name() == name {
+			return span
+		}
+	}
+	t.Fatalf("span %s has not been recorded", name)
+	return nil
+}
+
+// TestTracer tests whether the operations of a client are recorded as OpenTelemetry spans and the trace context is
+// propagated to the API server.
+func TestTracer(t *testing.T) {
+	client, server, recorder := newTracedClient(t)
+	_, err := client.Translate(&deeplclient.TranslationRequest{
+		Text:       "Hallo",
+		SourceLang: deeplclient.LangDE,
+		TargetLang: deeplclient.LangENUS,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	translateSpan := findSpan(t, recorder, "deepl.translate")
+	requestSpan := findSpan(t, recorder, "deepl.http_request")
+	if requestSpan.Parent().SpanID() != translateSpan.SpanContext().SpanID() {
+		t.Fatal("HTTP request span is no child of the translation span")
+	}
+	if translateSpan.SpanKind() != trace.SpanKindInternal || requestSpan.SpanKind() != trace.SpanKindClient {
+		t.Fatalf("unexpected span kinds: %s, %s", translateSpan.SpanKind(), requestSpan.SpanKind())
+	}
+	attributes := attribute.NewSet(translateSpan.Attributes()...)
+	if value, _ := attributes.Value(deeplclient.AttributeTargetLang); value.AsString() != "EN-US" {
+		t.Fatalf("unexpected target language attribute: %v", value.Emit())
+	}
+	attributes = attribute.NewSet(requestSpan.Attributes()...)
+	if value, _ := attributes.Value(deeplclient.AttributeHTTPStatusCode); value.AsInt64() != http.StatusOK {
+		t.Fatalf("unexpected status code attribute: %v", value.Emit())
+	}
+	requests := server.Requests()
+	if len(requests) != 1 {
+		t.Fatalf("expected 1 request, got %d", len(requests))
+	}
+	carrier := propagation.HeaderCarrier(requests[0].Header)
+	propagated := trace.SpanContextFromContext(propagation.TraceContext{}.Extract(context.Background(), carrier))
+	if propagated.TraceID() != requestSpan.SpanContext().TraceID() ||
+		propagated.SpanID() != requestSpan.SpanContext().SpanID() {
+		t.Fatalf("unexpected propagated trace context: %s", requests[0].Header.Get("traceparent"))
+	}
+}
+
+// TestTracerError tests whether failed operations are marked as failed.
+func TestTracerError(t *testing.T) {
+	client, server, recorder := newTracedClient(t)
+	server.InjectError(deepltest.ErrorInjection{Path: "usage", StatusCode: deeplclient.StatusQuotaExceeded})
+	if _, err := client.GetUsage(); !errors.Is(err, deeplclient.ErrQuotaExceeded) {
+		t.Fatalf("expected quota exceeded error, got: %v", err)
+	}
+	span := findSpan(t, recorder, "deepl.get_usage")
+	if span.Status().Code != codes.Error || len(span.Events()) == 0 {
+		t.Fatalf("expected failed span with error event, got status %v", span.Status())
+	}
+}
@@ -0,0 +1,129 @@
+package deeplclient
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sync"
+)
+
+const (
+	// maximum amount of texts per translation request - see https://www.deepl.com/docs-api/translate-text/
+	maxTextsPerRequest = 50
+	// default amount of chunks of a batch translated concurrently
+	defaultBatchConcurrency = 4
+)
+
+// textChunk contains the indices of the texts of a batch which are sent within the same request.
+type textChunk []int
+
+// TranslateBatch translates all given texts with the options of the given request (the field Text is ignored) and
+// returns the translations in the same order as the texts. See TranslateBatchWithContext for more details.
+func (client *Client) TranslateBatch(req *TranslationRequest, texts []string) ([]Translation, error) {
+	return client.TranslateBatchWithContext(context.Background(), req, texts)
+}
+
+// TranslateBatchWithContext translates all given texts with the options of the given request (the field Text is
+// ignored) and returns the translations in the same order as the texts.
+//
+// The texts are packed into as few requests as possible without exceeding the maximum body size and the maximum
+// amount of texts per request. Up to BatchConcurrency requests are sent concurrently. If any request fails, all other
+// requests are cancelled and the error is returned. Empty texts are not sent to the API server and result in an empty
+// translation.
+func (client *Client) TranslateBatchWithContext(ctx context.Context, req *TranslationRequest, texts []string) (
+	translations []Translation, err error) {
+	if err = client.validateLangs(req.SourceLang, req.TargetLang); err != nil {
+		return nil, err
+	}
+	values := req.values()
+	chunks, err := packTexts(len(values.Encode()), texts)
+	if err != nil {
+		return nil, err
+	}
+
+	translations = make([]Translation, len(texts))
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var wg sync.WaitGroup
+	var errOnce sync.Once
+	semaphore := make(chan struct{}, client.BatchConcurrency())
+	for _, chunk := range chunks {
+		select {
+		case semaphore <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func(chunk textChunk) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+			if chunkErr := client.translateChunk(ctx, values, texts, chunk, translations); chunkErr != nil {
+				errOnce.Do(func() {
+					err = chunkErr
+					cancel()
+				})
+			}
+		}(chunk)
+	}
+	wg.Wait()
+	if err == nil {
+		err = ctx.Err()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return translations, nil
+}
+
+// translateChunk translates the texts referenced by the given chunk and stores the results at the same indices within
+// the translations.
+func (client *Client) translateChunk(ctx context.Context, values *url.Values, texts []string, chunk textChunk,
+	translations []Translation) error {
+	chunkValues := url.Values{}
+	for key, value := range *values {
+		chunkValues[key] = value
+	}
+	for _, index := range chunk {
+		chunkValues.Add("text", texts[index])
+	}
+	resp, err := client.translate(ctx, &chunkValues)
+	if err != nil {
+		return err
+	}
+	if len(resp.Translations) != len(chunk) {
+		return fmt.Errorf("server returned %d translations for %d texts", len(resp.Translations), len(chunk))
+	}
+	for i, index := range chunk {
+		translations[index] = resp.Translations[i]
+	}
+	return nil
+}
+
+// packTexts distributes the non-empty texts into as few chunks as possible. Each chunk is small enough to be sent
+// within one request, whose url encoded options already take up the given base size.
+func packTexts(baseSize int, texts []string) (chunks []textChunk, err error) {
+	var current textChunk
+	currentSize := baseSize
+	for index, text := range texts {
+		if len(text) == 0 {
+			continue
+		}
+		// each text is encoded as an additional "&text=..." parameter
+		size := len("&text=") + len(url.QueryEscape(text))
+		if baseSize+size > maxBodySize {
+			return nil, fmt.Errorf("text at index %d exceeds the maximum body size of %d", index, maxBodySize)
+		}
+		if len(current) == maxTextsPerRequest || currentSize+size > maxBodySize {
+			chunks = append(chunks, current)
+			current, currentSize = nil, baseSize
+		}
+		current = append(current, index)
+		currentSize += size
+	}
+	if len(current) > 0 {
+		chunks = append(chunks, current)
+	}
+	return
+}
@@ -0,0 +1,288 @@
+package deeplclient
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Cache stores translation responses by a key derived from the translation request. Implementations must be safe for
+// concurrent use.
+type Cache interface {
+	// Get returns the response stored for the key. The second return value is false if no (unexpired) response is
+	// stored.
+	Get(key string) (*TranslationResponse, bool)
+	// Set stores the response for the key.
+	Set(key string, resp *TranslationResponse)
+}
+
+// CacheKey derives the key of a translation request. All options affecting the translation are considered: the text,
+// the source and target language, the formality, the glossary and the tag handling options.
+func CacheKey(req *TranslationRequest) string {
+	data, _ := json.Marshal([]interface{}{
+		req.Text, req.SourceLang, req.TargetLang, req.Formality, req.GlossaryId, req.TagHandling,
+		req.NonSplittingTags, req.IgnoreTags, req.DoNotSplitSentences, req.PreserveFormatting,
+	})
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:])
+}
+
+// copyTranslationResponse returns a deep copy of the response, so cached responses cannot be modified by callers.
+func copyTranslationResponse(resp *TranslationResponse) *TranslationResponse {
+	return &TranslationResponse{Translations: append([]Translation(nil), resp.Translations...)}
+}
+
+// lruCacheEntry is an element of the LRU cache.
+type lruCacheEntry struct {
+	key     string
+	resp    *TranslationResponse
+	expires time.Time
+}
+
+// LRUCache is an in-memory Cache which evicts the least recently used responses if its capacity is reached.
+type LRUCache struct {
+	mutex    sync.Mutex
+	capacity int
+	ttl      time.Duration
+	entries  map[string]*list.Element
+	order    *list.List
+}
+
+// NewLRUCache creates a new in-memory cache storing up to capacity responses. Responses expire after the given
+// time to live, which never happens if ttl is zero.
+func NewLRUCache(capacity int, ttl time.Duration) *LRUCache {
+	return &LRUCache{
+		capacity: capacity,
+		ttl:      ttl,
+		entries:  map[string]*list.Element{},
+		order:    list.New(),
+	}
+}
+
+// Get returns the response stored for the key and marks it as recently used.
+func (cache *LRUCache) Get(key string) (*TranslationResponse, bool) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	element, ok := cache.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := element.Value.(*lruCacheEntry)
+	if !entry.expires.IsZero() && time.Now().After(entry.expires) {
+		cache.order.Remove(element)
+		delete(cache.entries, key)
+		return nil, false
+	}
+	cache.order.MoveToFront(element)
+	return copyTranslationResponse(entry.resp), true
+}
+
+// Set stores the response for the key and evicts the least recently used response if the capacity is exceeded.
+func (cache *LRUCache) Set(key string, resp *TranslationResponse) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	entry := &lruCacheEntry{key: key, resp: copyTranslationResponse(resp)}
+	if cache.ttl > 0 {
+		entry.expires = time.Now().Add(cache.ttl)
+	}
+	if element, ok := cache.entries[key]; ok {
+		element.Value = entry
+		cache.order.MoveToFront(element)
+		return
+	}
+	cache.entries[key] = cache.order.PushFront(entry)
+	for cache.capacity > 0 && cache.order.Len() > cache.capacity {
+		oldest := cache.order.Back()
+		cache.order.Remove(oldest)
+		delete(cache.entries, oldest.Value.(*lruCacheEntry).key)
+	}
+}
+
+// Len returns the amount of stored responses including expired ones which have not been evicted yet.
+func (cache *LRUCache) Len() int {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	return cache.order.Len()
+}
+
+// fileCacheEntry is the content of a file of the file cache.
+type fileCacheEntry struct {
+	Expires  time.Time            `json:"expires"`
+	Response *TranslationResponse `json:"response"`
+}
+
+// FileCache is a persistent Cache which stores each response as JSON file within a directory.
+type FileCache struct {
+	dir string
+	ttl time.Duration
+}
+
+// NewFileCache creates a new persistent cache storing the responses in the given directory, which is created if
+// necessary. Responses expire after the given time to live, which never happens if ttl is zero.
+func NewFileCache(dir string, ttl time.Duration) (*FileCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileCache{dir: dir, ttl: ttl}, nil
+}
+
+// path returns the path of the file storing the response for the key. Keys created by CacheKey are safe to be used
+// as file names, other keys are hashed.
+func (cache *FileCache) path(key string) string {
+	if _, err := hex.DecodeString(key); err != nil || len(key) != sha256.Size*2 {
+		hash := sha256.Sum256([]byte(key))
+		key = hex.EncodeToString(hash[:])
+	}
+	return filepath.Join(cache.dir, key+".json")
+}
+
+// Get returns the response stored for the key. Expired responses are removed.
+func (cache *FileCache) Get(key string) (*TranslationResponse, bool) {
+	path := cache.path(key)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	entry := &fileCacheEntry{}
+	if err = json.Unmarshal(data, entry); err != nil || entry.Response == nil {
+		return nil, false
+	}
+	if !entry.Expires.IsZero() && time.Now().After(entry.Expires) {
+		_ = os.Remove(path)
+		return nil, false
+	}
+	return entry.Response, true
+}
+
+// Set stores the response for the key. Errors are ignored, as a response which could not be stored is simply not
+// found later on.
+func (cache *FileCache) Set(key string, resp *TranslationResponse) {
+	entry := &fileCacheEntry{Response: resp}
+	if cache.ttl > 0 {
+		entry.Expires = time.Now().Add(cache.ttl)
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	// write into a temporary file first, so concurrent readers never see partially written files
+	file, err := os.CreateTemp(cache.dir, ".tmp-*")
+	if err != nil {
+		return
+	}
+	_, err = file.Write(data)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(file.Name(), cache.path(key))
+	}
+	if err != nil {
+		_ = os.Remove(file.Name())
+	}
+}
+
+// CacheStats contains the statistics of a caching translator.
+type CacheStats struct {
+	// Hits is the amount of translations served from the cache.
+	Hits int64
+	// Misses is the amount of translations requested from the decorated translator.
+	Misses int64
+}
+
+// HitRatio returns the fraction of translations served from the cache.
+func (stats CacheStats) HitRatio() float64 {
+	if stats.Hits+stats.Misses == 0 {
+		return 0
+	}
+	return float64(stats.Hits) / float64(stats.Hits+stats.Misses)
+}
+
+// TextCachingTranslator is a decorator which serves repeated text translations from a cache.
+type TextCachingTranslator struct {
+	next   TextTranslator
+	cache  Cache
+	hits   int64
+	misses int64
+}
+
+// WithTextCache decorates the text translator, so text translations are served from the given cache if possible. The
+// returned translator is a *TextCachingTranslator, which reports the cache statistics.
+func WithTextCache(next TextTranslator, cache Cache) TextTranslator {
+	return &TextCachingTranslator{next: next, cache: cache}
+}
+
+// Stats returns the cache statistics of the translator.
+func (translator *TextCachingTranslator) Stats() CacheStats {
+	return CacheStats{
+		Hits:   atomic.LoadInt64(&translator.hits),
+		Misses: atomic.LoadInt64(&translator.misses),
+	}
+}
+
+// TranslateWithContext returns the cached translation if available. Otherwise, the decorated translator is called
+// and its response is cached.
+func (translator *TextCachingTranslator) TranslateWithContext(ctx context.Context, req *TranslationRequest) (
+	*TranslationResponse, error) {
+	key := CacheKey(req)
+	if resp, ok := translator.cache.Get(key); ok {
+		atomic.AddInt64(&translator.hits, 1)
+		return resp, nil
+	}
+	atomic.AddInt64(&translator.misses, 1)
+	resp, err := translator.next.TranslateWithContext(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if resp == nil {
+		return nil, errors.New("translator returned neither a response nor an error")
+	}
+	translator.cache.Set(key, copyTranslationResponse(resp))
+	return resp, nil
+}
+
+// CachingTranslator is a decorator which serves repeated text translations from a cache. All other calls are passed
+// to the decorated translator.
+type CachingTranslator struct {
+	*TextCachingTranslator
+	next Translator
+}
+
+// WithCache decorates the translator, so text translations are served from the given cache if possible.
+func WithCache(next Translator, cache Cache) *CachingTranslator {
+	return &CachingTranslator{
+		TextCachingTranslator: WithTextCache(next, cache).(*TextCachingTranslator),
+		next:                  next,
+	}
+}
+
+// StartDocumentTranslateWithContext calls the decorated translator.
+func (translator *CachingTranslator) StartDocumentTranslateWithContext(ctx context.Context,
+	req *DocumentTranslationStartRequest) (*DocumentTranslationStartResponse, error) {
+	return translator.next.StartDocumentTranslateWithContext(ctx, req)
+}
+
+// CheckDocumentTranslationStatusWithContext calls the decorated translator.
+func (translator *CachingTranslator) CheckDocumentTranslationStatusWithContext(ctx context.Context,
+	req *DocumentTranslationStatusRequest) (*DocumentTranslationStatusResponse, error) {
+	return translator.next.CheckDocumentTranslationStatusWithContext(ctx, req)
+}
+
+// DownloadTranslatedDocumentWithContext calls the decorated translator.
+func (translator *CachingTranslator) DownloadTranslatedDocumentWithContext(ctx context.Context,
+	req *DocumentTranslationDownloadRequest) ([]byte, error) {
+	return translator.next.DownloadTranslatedDocumentWithContext(ctx, req)
+}
+
+// GetUsageWithContext calls the decorated translator.
+func (translator *CachingTranslator) GetUsageWithContext(ctx context.Context) (*UsageResponse, error) {
+	return translator.next.GetUsageWithContext(ctx)
+}
@@ -0,0 +1,136 @@
+package deeplclient
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// testCacheResponse returns a translation response containing the given text.
+func testCacheResponse(text string) *TranslationResponse {
+	return &TranslationResponse{Translations: []Translation{{DetectedSourceLanguage: LangDE, Text: text}}}
+}
+
+// TestCacheKey tests whether all options affecting the translation change the cache key.
+func TestCacheKey(t *testing.T) {
+	base := TranslationRequest{Text: "Hallo", TargetLang: LangENGB}
+	modified := []TranslationRequest{base, base, base, base, base, base}
+	modified[0].Text = "Hallo!"
+	modified[1].SourceLang = LangDE
+	modified[2].TargetLang = LangENUS
+	modified[3].Formality = FormalityMore
+	modified[4].GlossaryId = "glossary"
+	modified[5].TagHandling = []string{"xml"}
+	key := CacheKey(&base)
+	if copied := base; CacheKey(&copied) != key {
+		t.Fatal("equal requests have different cache keys")
+	}
+	for i := range modified {
+		if CacheKey(&modified[i]) == key {
+			t.Errorf("modified request %d has the same cache key", i)
+		}
+	}
+}
+
+// TestLRUCache tests the eviction and expiration of the in-memory cache.
+func TestLRUCache(t *testing.T) {
+	cache := NewLRUCache(2, 0)
+	cache.Set("a", testCacheResponse("a"))
+	cache.Set("b", testCacheResponse("b"))
+	if _, ok := cache.Get("a"); !ok {
+		t.Fatal("expected cached response for a")
+	}
+	// b is the least recently used response now
+	cache.Set("c", testCacheResponse("c"))
+	if _, ok := cache.Get("b"); ok {
+		t.Fatal("expected b to be evicted")
+	}
+	if resp, ok := cache.Get("c"); !ok || resp.Translations[0].Text != "c" {
+		t.Fatalf("unexpected cached response for c: %+v", resp)
+	}
+	if cache.Len() != 2 {
+		t.Fatalf("expected 2 cached responses, got %d", cache.Len())
+	}
+
+	cache = NewLRUCache(0, time.Millisecond)
+	cache.Set("a", testCacheResponse("a"))
+	time.Sleep(5 * time.Millisecond)
+	if _, ok := cache.Get("a"); ok {
+		t.Fatal("expected a to be expired")
+	}
+}
+
+// TestFileCache tests whether responses are persisted across file cache instances.
+func TestFileCache(t *testing.T) {
+	dir := t.TempDir()
+	cache, err := NewFileCache(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := CacheKey(&TranslationRequest{Text: "Hallo", TargetLang: LangENGB})
+	cache.Set(key, testCacheResponse("Hello"))
+	cache.Set("not a hash", testCacheResponse("Hi"))
+	if cache, err = NewFileCache(dir, 0); err != nil {
+		t.Fatal(err)
+	}
+	if resp, ok := cache.Get(key); !ok || resp.Translations[0].Text != "Hello" {
+		t.Fatalf("unexpected cached response: %+v", resp)
+	}
+	if resp, ok := cache.Get("not a hash"); !ok || resp.Translations[0].Text != "Hi" {
+		t.Fatalf("unexpected cached response: %+v", resp)
+	}
+
+	if cache, err = NewFileCache(dir, time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	cache.Set(key, testCacheResponse("Hello"))
+	time.Sleep(5 * time.Millisecond)
+	if _, ok := cache.Get(key); ok {
+		t.Fatal("expected cached response to be expired")
+	}
+}
+
+// TestCachingTranslator tests whether repeated translations are served from the cache.
+func TestCachingTranslator(t *testing.T) {
+	client, server := newFakeClient(t)
+	translator := WithCache(client, NewLRUCache(10, time.Minute))
+	req := &TranslationRequest{Text: "Hallo Welt!", TargetLang: LangENGB}
+	for i := 0; i < 3; i++ {
+		resp, err := translator.TranslateWithContext(context.Background(), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Translations[0].Text != "[EN-GB] Hallo Welt!" {
+			t.Fatalf("unexpected translation response: %+v", resp)
+		}
+		// modifying the response must not affect the cache
+		resp.Translations[0].Text = ""
+	}
+	if len(server.Requests()) != 1 {
+		t.Fatalf("expected 1 request, got %d", len(server.Requests()))
+	}
+	if stats := translator.Stats(); stats.Hits != 2 || stats.Misses != 1 {
+		t.Fatalf("unexpected cache statistics: %+v", stats)
+	}
+}
+
+// TestTextCachingTranslator tests whether translators implementing only text translations can be cached.
+func TestTextCachingTranslator(t *testing.T) {
+	flaky := &flakyTextTranslator{}
+	translator := WithTextCache(flaky, NewLRUCache(10, 0))
+	for i := 0; i < 2; i++ {
+		resp, err := translator.TranslateWithContext(context.Background(), &TranslationRequest{Text: "Hallo"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Translations[0].Text != "Hallo" {
+			t.Fatalf("unexpected translation response: %+v", resp)
+		}
+	}
+	if flaky.calls != 1 {
+		t.Fatalf("expected 1 call, got %d", flaky.calls)
+	}
+	if stats := translator.(*TextCachingTranslator).Stats(); stats.Hits != 1 || stats.Misses != 1 {
+		t.Fatalf("unexpected cache statistics: %+v", stats)
+	}
+}
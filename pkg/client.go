@@ -2,138 +2,347 @@ package deeplclient
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
+	"time"
+	"unicode/utf8"
 )
 
 const (
 	// StatusQuotaExceeded is the unofficial internal HTTP status code for "Quota exceeded"
 	StatusQuotaExceeded = 456
-	usageFunctionUri    = "usage"
+	// StatusTooManyRequestsHighLoad is the unofficial internal HTTP status code for "Too many requests" which is
+	// returned if the DeepL API is under high load
+	StatusTooManyRequestsHighLoad = 529
+	usageFunctionUri              = "usage"
 	// maximum body size - see https://www.deepl.com/docs-api/accessing-the-api/limits/
 	maxBodySize = 128 * 1024
 )
 
 // Client allows easy access to the DeepL API by providing methods for each API function.
+//
+// Clients should be created with NewClient, which validates the whole configuration up front. The configuration of a
+// client cannot be changed after its creation, so a Client is safe for concurrent use by multiple goroutines.
 type Client struct {
 	*http.Client
 	// AuthKey stores the authentication key required to get access DeepL's API.
-	AuthKey     []byte
+	//
+	// Deprecated: the auth key is set by NewClient and must not be modified once the client is in use. The field will
+	// be unexported in a future version.
+	AuthKey []byte
+	// EndpointUrl is the URL of the API server including the version path.
+	//
+	// Deprecated: the endpoint is set by NewClient and WithEndpoint and must not be modified once the client is in
+	// use. The field will be unexported in a future version.
 	EndpointUrl string
+
+	// retryPolicy defines whether and how requests failing with a temporary error are sent again. If nil, every
+	// request is sent only once.
+	retryPolicy *RetryPolicy
+	// rateLimiter limits the amount of requests and characters sent to the API server. If nil, requests are not
+	// limited on the client side.
+	rateLimiter *RateLimiter
+	// batchConcurrency is the maximum amount of requests sent concurrently by a batch translation, if set
+	batchConcurrency int
+	// splitOversizedTexts enables splitting of texts which exceed the maximum body size of a translation request
+	splitOversizedTexts bool
+	// userAgent is sent as User-Agent header of every request, if not empty
+	userAgent string
+	// header contains additional headers sent with every request
+	header http.Header
+	// logger receives debug messages about the requests, if not nil
+	logger Logger
+	// middlewares wrap the sending of every request
+	middlewares []Middleware
+	// metrics receives metrics about the requests, if not nil
+	metrics MetricsCollector
+	// tracer starts spans for the operations, if not nil
+	tracer Tracer
+	// quota rejects requests exceeding the character budget, if not nil
+	quota *QuotaGuard
+	// tenants meters the characters per tenant and enforces their budgets, if not nil
+	tenants *TenantLedger
+	// keys contains the auth keys used instead of AuthKey, if not nil
+	keys *KeyPool
+
+	// languages caches the languages supported by the API server
+	languages languageRegistry
+	// attachments bind components (e.g. a quota guard) to the client once all options have been applied
+	attachments []attachment
+}
+
+// RetryPolicy returns a copy of the policy for retrying requests which failed with a temporary error (see
+// WithRetryPolicy), or nil if every request is sent only once.
+func (client *Client) RetryPolicy() *RetryPolicy {
+	if client.retryPolicy == nil {
+		return nil
+	}
+	copied := *client.retryPolicy
+	return &copied
 }
 
-// handleApiError is an internally used function to parse the status of a finished HTTP request. If any error occurred
-// (detected by status code or non-valid JSON response), it will be parsed into a known client API error
+// RateLimiter returns the client-side rate limiter (see WithRateLimit), or nil if requests are not limited on the
+// client side.
+func (client *Client) RateLimiter() *RateLimiter {
+	return client.rateLimiter
+}
+
+// BatchConcurrency returns the maximum amount of requests sent concurrently by a batch translation (see
+// WithBatchConcurrency).
+func (client *Client) BatchConcurrency() int {
+	if client.batchConcurrency < 1 {
+		return defaultBatchConcurrency
+	}
+	return client.batchConcurrency
+}
+
+// SplitOversizedTexts returns whether texts exceeding the maximum body size of a translation request are split (see
+// WithSplitOversizedTexts).
+func (client *Client) SplitOversizedTexts() bool {
+	return client.splitOversizedTexts
+}
+
+// handleApiError is an internally used function to parse the status of a finished HTTP request. Only successful
+// responses are returned. Any other status code is parsed into the dedicated client API error, which contains the
+// details of the response, or into an APIError if the status code is not documented.
 func handleApiError(resp *http.Response) (returnResponse bool, err error) {
 	switch resp.StatusCode {
-	case http.StatusOK:
-		return true, nil
-	case http.StatusBadRequest:
-		err = &WrongRequestErr{}
-		break
-	case http.StatusForbidden:
-		err = &AuthFailedErr{}
+	case http.StatusOK, http.StatusCreated, http.StatusNoContent:
 		return true, nil
-	case http.StatusRequestEntityTooLarge:
-		err = &RequestEntityTooLargeErr{}
-		break
-	case http.StatusTooManyRequests:
-		err = &TooManyRequestsErr{}
-		break
-	case http.StatusNotFound:
-		err = &NotFoundErr{}
-		break
-	case StatusQuotaExceeded:
-		err = &QuotaExceededErr{}
-		break
-	default:
-		err = UnwrappedApiResponseCodeErr(resp.StatusCode)
-		return false, err
 	}
-	if err != nil {
-		return false, err
-	}
-	if jsonErr := json.NewDecoder(resp.Body).Decode(err); jsonErr != nil {
-		err = jsonErr
+	apiErr := newAPIError(resp)
+	if status, ok := statusErrors[resp.StatusCode]; ok && status.wrap != nil {
+		return false, status.wrap(apiErr)
 	}
+	return false, apiErr
+}
 
-	return false, err
+// apiRequest contains all information required to send a request to the API server.
+type apiRequest struct {
+	// endpoint is the API function URI without IDs, which is used for metrics
+	endpoint    string
+	method      string
+	requestUrl  string
+	contentType string
+	// accept is sent as the accepted content type of the response, if not empty
+	accept string
+	// body is sent as a whole and can therefore be sent again for every attempt
+	body []byte
+	// stream is sent instead of body if set. As it can only be read once, the request is never retried.
+	stream io.Reader
+	// characters is the amount of characters to be translated, which is accounted by the rate limiter
+	characters int
+	// keyId is the ID of the pooled auth key used by the current attempt, if any
+	keyId string
 }
 
-// doApiFunctionWithMultipartForm is an internally used function to execute API functions which require upload
-// of complex structures like files etc. The param uri should not begin with a slash character
-func (client *Client) doApiFunctionWithMultipartForm(uri, method string, boundary string, body *bytes.Buffer) (
-	resp *http.Response, err error) {
-	// create new http request
-	var req *http.Request
-	requestUrl := fmt.Sprintf("%s%s", client.EndpointUrl, uri)
-	if req, err = http.NewRequest(method, requestUrl, body); err != nil {
-		return
+// newBody returns a reader for the body of the next attempt.
+func (apiReq *apiRequest) newBody() io.Reader {
+	if apiReq.stream != nil {
+		return apiReq.stream
 	}
-	// add header to allow the server to identify the POST request and auth key
-	req.Header.Set("Authorization", "DeepL-Auth-Key "+string(client.AuthKey))
-	req.Header.Set("Content-Type", `multipart/form-data; boundary="`+boundary+`"`)
-
-	if resp, err = client.Do(req); err != nil {
-		return nil, err
+	if apiReq.body != nil {
+		return bytes.NewReader(apiReq.body)
 	}
+	return nil
+}
 
-	// check status code and wrap response
-	returnResponse, err := handleApiError(resp)
-	// in case response is not valid/confusing, omit it
-	if !returnResponse {
-		resp = nil
-	}
-	return
+// doApiFunctionWithMultipartForm is an internally used function to execute API functions which require upload
+// of complex structures like files etc. The param uri should not begin with a slash character. The request is bound
+// to the given context, so cancelling it aborts the upload.
+func (client *Client) doApiFunctionWithMultipartForm(ctx context.Context, uri, method string, boundary string,
+	body *bytes.Buffer) (resp *http.Response, err error) {
+	return client.doRequest(ctx, &apiRequest{
+		endpoint:    endpointLabel(uri),
+		method:      method,
+		requestUrl:  fmt.Sprintf("%s%s", client.EndpointUrl, uri),
+		contentType: `multipart/form-data; boundary="` + boundary + `"`,
+		// the buffered body is kept as a whole, so it can be sent again if the request has to be retried
+		body: body.Bytes(),
+	})
+}
+
+// doApiFunctionWithMultipartStream is an internally used function to execute API functions which require upload
+// of large files. In contrast to doApiFunctionWithMultipartForm, the body is streamed and therefore never retried.
+func (client *Client) doApiFunctionWithMultipartStream(ctx context.Context, uri, method string, boundary string,
+	body io.Reader) (resp *http.Response, err error) {
+	return client.doRequest(ctx, &apiRequest{
+		endpoint:    endpointLabel(uri),
+		method:      method,
+		requestUrl:  fmt.Sprintf("%s%s", client.EndpointUrl, uri),
+		contentType: `multipart/form-data; boundary="` + boundary + `"`,
+		stream:      body,
+	})
 }
 
 // doApiFunction is an internally used function to execute API functions more easily. The param uri should not begin with
-// a slash character.
-func (client *Client) doApiFunction(uri, method string, values *url.Values) (resp *http.Response, err error) {
-	// create new http request
-	var req *http.Request
-	var requestUrl string
-	var body io.Reader
+// a slash character. The request is bound to the given context, so cancelling it aborts the request and the reading of
+// the response body.
+func (client *Client) doApiFunction(ctx context.Context, uri, method string, values *url.Values) (
+	resp *http.Response, err error) {
+	return client.doApiFunctionWithAccept(ctx, uri, method, values, "")
+}
+
+// doApiFunctionWithAccept is an internally used function to execute API functions which respond with a specific
+// content type passed as accept. An empty accept lets the server decide about the content type.
+func (client *Client) doApiFunctionWithAccept(ctx context.Context, uri, method string, values *url.Values,
+	accept string) (resp *http.Response, err error) {
+	apiReq := &apiRequest{
+		endpoint:    endpointLabel(uri),
+		method:      method,
+		contentType: "application/x-www-form-urlencoded",
+		accept:      accept,
+	}
 	if method == http.MethodPost {
-		requestUrl = fmt.Sprintf("%s%s", client.EndpointUrl, uri)
+		apiReq.requestUrl = fmt.Sprintf("%s%s", client.EndpointUrl, uri)
 		valuesEncoded := values.Encode()
 		if len(valuesEncoded) > maxBodySize {
 			return nil, errors.New("body size should not exceed maximum of " + strconv.Itoa(maxBodySize))
 		}
-		body = strings.NewReader(valuesEncoded)
+		apiReq.body = []byte(valuesEncoded)
 	} else {
-		requestUrl = fmt.Sprintf("%s%s?%s", client.EndpointUrl, uri, values.Encode())
+		apiReq.requestUrl = fmt.Sprintf("%s%s?%s", client.EndpointUrl, uri, values.Encode())
 	}
-	if req, err = http.NewRequest(method, requestUrl, body); err != nil {
-		return
+	// count the characters to be translated, so they can be limited by the rate limiter
+	for _, text := range (*values)["text"] {
+		apiReq.characters += utf8.RuneCountInString(text)
 	}
-	// add header to allow the server to identify the POST request and auth key
-	req.Header.Set("Authorization", "DeepL-Auth-Key "+string(client.AuthKey))
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return client.doRequest(ctx, apiReq)
+}
 
-	if resp, err = client.Do(req); err != nil {
+// doRequest is an internally used function which sends the request to the API server. If the client uses a key pool,
+// the request is sent again with the next available key as long as it fails because of the quota or auth key.
+func (client *Client) doRequest(ctx context.Context, apiReq *apiRequest) (resp *http.Response, err error) {
+	ctx, span := client.startSpan(ctx, "deepl.http_request")
+	defer func() {
+		endSpan(span, err)
+	}()
+	span.SetAttribute(AttributeHTTPMethod, apiReq.method)
+	span.SetAttribute(AttributeEndpoint, apiReq.endpoint)
+	tried := map[*pooledKey]bool{}
+	key, err := client.keys.acquire(ctx, tried, apiReq.characters)
+	if err != nil {
 		return nil, err
 	}
-
-	// check status code and wrap response
-	returnResponse, err := handleApiError(resp)
-	// in case response is not valid/confusing, omit it
-	if !returnResponse {
-		resp = nil
+	for {
+		resp, err = client.sendRequest(ctx, span, apiReq, key)
+		// streamed bodies cannot be sent again
+		if apiReq.stream != nil || !client.keys.failover(ctx, key, err) {
+			break
+		}
+		tried[key] = true
+		next, _ := client.keys.acquire(ctx, tried, apiReq.characters)
+		if next == nil {
+			break
+		}
+		client.debug("failing over to next deepl auth key", "method", apiReq.method, "url",
+			redactUrl(apiReq.requestUrl), "key", key.id, "next", next.id, "error", err.Error())
+		key = next
+	}
+	if err == nil {
+		client.keys.served(ctx, key, apiReq.endpoint)
 	}
 	return
 }
 
+// sendRequest is an internally used function which sends the request with the given key of the key pool (or the auth
+// key of the client if nil) and replays it according to the retry policy of the client as long as the server responds
+// with a temporary error. Every attempt waits for the rate limiter of the client.
+func (client *Client) sendRequest(ctx context.Context, span Span, apiReq *apiRequest, key *pooledKey) (
+	resp *http.Response, err error) {
+	maxAttempts := client.retryPolicy.attempts()
+	if apiReq.stream != nil {
+		maxAttempts = 1
+	}
+	authKey := string(client.AuthKey)
+	apiReq.keyId = ""
+	if key != nil {
+		authKey = key.authKey
+		apiReq.keyId = key.id
+		span.SetAttribute(AttributeAuthKeyId, key.id)
+	}
+	for attempt := 1; ; attempt++ {
+		span.SetAttribute(AttributeRetryCount, attempt-1)
+		if err = client.rateLimiter.Wait(ctx, apiReq.characters); err != nil {
+			return nil, err
+		}
+		// create new http request
+		var req *http.Request
+		if req, err = http.NewRequestWithContext(ctx, apiReq.method, apiReq.requestUrl, apiReq.newBody()); err != nil {
+			return
+		}
+		for name, values := range client.header {
+			req.Header[name] = append([]string(nil), values...)
+		}
+		if client.userAgent != "" {
+			req.Header.Set("User-Agent", client.userAgent)
+		}
+		// add header to allow the server to identify the POST request and auth key
+		req.Header.Set("Authorization", "DeepL-Auth-Key "+authKey)
+		req.Header.Set("Content-Type", apiReq.contentType)
+		if apiReq.accept != "" {
+			req.Header.Set("Accept", apiReq.accept)
+		}
+		client.injectTraceContext(ctx, req.Header)
+
+		var delay time.Duration
+		start := time.Now()
+		resp, err = client.roundTrip(req)
+		duration := time.Since(start)
+		client.logAttempt(apiReq, attempt, duration, resp, err)
+		client.observeRequest(apiReq, duration, resp)
+		if resp != nil {
+			span.SetAttribute(AttributeHTTPStatusCode, resp.StatusCode)
+		}
+		if err == nil && isTooManyRequestsStatus(resp.StatusCode) {
+			// let the rate limiter slow down before the request is sent again
+			client.rateLimiter.Throttle()
+		}
+		if err != nil {
+			// errors caused by the context itself cannot be resolved by trying again and requests which may have been
+			// processed already must not be sent twice
+			if ctx.Err() != nil || attempt >= maxAttempts || !isRetryableNetworkErr(err) {
+				return nil, err
+			}
+			delay = client.retryPolicy.delay(attempt, nil)
+		} else if attempt < maxAttempts && isRetryableStatus(resp.StatusCode) {
+			delay = client.retryPolicy.delay(attempt, resp.Header)
+			// the connection can only be reused if the body has been read completely
+			_, _ = io.Copy(io.Discard, resp.Body)
+			_ = resp.Body.Close()
+		} else {
+			// check status code and wrap response
+			var returnResponse bool
+			returnResponse, err = handleApiError(resp)
+			// in case response is not valid/confusing, omit it
+			if !returnResponse {
+				_ = resp.Body.Close()
+				resp = nil
+			}
+			return
+		}
+		client.debug("retrying deepl api request", "method", apiReq.method, "url", redactUrl(apiReq.requestUrl),
+			"attempt", attempt, "delay", delay)
+		if err = sleepContext(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
 // ApiLang is a wrapper type for languages used in requests/responses within the translation function.
 type ApiLang string
 
 const (
+	// LangAR Arabic
+	LangAR = ApiLang("AR")
 	// 	LangBG Bulgarian
 	LangBG = ApiLang("BG")
 	// LangCS Czech
@@ -144,8 +353,12 @@ const (
 	LangDE = ApiLang("DE")
 	// LangEL Greek
 	LangEL = ApiLang("EL")
-	// LangEN English
+	// LangEN English (source language only, use LangENGB or LangENUS as target language)
 	LangEN = ApiLang("EN")
+	// LangENGB English (British, target language only)
+	LangENGB = ApiLang("EN-GB")
+	// LangENUS English (American, target language only)
+	LangENUS = ApiLang("EN-US")
 	// LangES Spanish
 	LangES = ApiLang("ES")
 	// LangET Estonian
@@ -162,16 +375,25 @@ const (
 	LangIT = ApiLang("IT")
 	// LangJA Japanese
 	LangJA = ApiLang("JA")
+	// LangKO Korean
+	LangKO = ApiLang("KO")
 	// LangLT Lithuanian
 	LangLT = ApiLang("LT")
 	// LangLV Latvian
 	LangLV = ApiLang("LV")
+	// LangNB Norwegian (Bokmål)
+	LangNB = ApiLang("NB")
 	// LangNL Dutch
 	LangNL = ApiLang("NL")
 	// LangPL Polish
 	LangPL = ApiLang("PL")
-	// LangPT Portuguese (all Portuguese varieties mixed)
+	// LangPT Portuguese (all Portuguese varieties mixed, source language only, use LangPTBR or LangPTPT as target
+	// language)
 	LangPT = ApiLang("PT")
+	// LangPTBR Portuguese (Brazilian, target language only)
+	LangPTBR = ApiLang("PT-BR")
+	// LangPTPT Portuguese (all Portuguese varieties excluding Brazilian Portuguese, target language only)
+	LangPTPT = ApiLang("PT-PT")
 	// LangRO Romanian
 	LangRO = ApiLang("RO")
 	// LangRU Russian
@@ -186,75 +408,126 @@ const (
 	LangTR = ApiLang("TR")
 	// LangUK Ukrainian
 	LangUK = ApiLang("UK")
-	// LangZH Chinese
+	// LangZH Chinese (as target language unspecified variant for backward compatibility, prefer LangZHHANS or
+	// LangZHHANT)
 	LangZH = ApiLang("ZH")
+	// LangZHHANS Chinese (simplified, target language only)
+	LangZHHANS = ApiLang("ZH-HANS")
+	// LangZHHANT Chinese (traditional, target language only)
+	LangZHHANT = ApiLang("ZH-HANT")
 )
 
+// langUsage describes whether a language can be used as source and/or target language.
+type langUsage uint8
+
+const (
+	langUsageSource = langUsage(1 << iota)
+	langUsageTarget
+	langUsageSourceAndTarget = langUsageSource | langUsageTarget
+)
+
+// knownLangs contains all languages known by this library and how they can be used.
+var knownLangs = map[ApiLang]langUsage{
+	LangAR:     langUsageSourceAndTarget,
+	LangBG:     langUsageSourceAndTarget,
+	LangCS:     langUsageSourceAndTarget,
+	LangDA:     langUsageSourceAndTarget,
+	LangDE:     langUsageSourceAndTarget,
+	LangEL:     langUsageSourceAndTarget,
+	LangEN:     langUsageSource,
+	LangENGB:   langUsageTarget,
+	LangENUS:   langUsageTarget,
+	LangES:     langUsageSourceAndTarget,
+	LangET:     langUsageSourceAndTarget,
+	LangFI:     langUsageSourceAndTarget,
+	LangFR:     langUsageSourceAndTarget,
+	LangHU:     langUsageSourceAndTarget,
+	LangID:     langUsageSourceAndTarget,
+	LangIT:     langUsageSourceAndTarget,
+	LangJA:     langUsageSourceAndTarget,
+	LangKO:     langUsageSourceAndTarget,
+	LangLT:     langUsageSourceAndTarget,
+	LangLV:     langUsageSourceAndTarget,
+	LangNB:     langUsageSourceAndTarget,
+	LangNL:     langUsageSourceAndTarget,
+	LangPL:     langUsageSourceAndTarget,
+	LangPT:     langUsageSource,
+	LangPTBR:   langUsageTarget,
+	LangPTPT:   langUsageTarget,
+	LangRO:     langUsageSourceAndTarget,
+	LangRU:     langUsageSourceAndTarget,
+	LangSK:     langUsageSourceAndTarget,
+	LangSL:     langUsageSourceAndTarget,
+	LangSV:     langUsageSourceAndTarget,
+	LangTR:     langUsageSourceAndTarget,
+	LangUK:     langUsageSourceAndTarget,
+	LangZH:     langUsageSourceAndTarget,
+	LangZHHANS: langUsageTarget,
+	LangZHHANT: langUsageTarget,
+}
+
 // String returns the very basic string representation of the API language.
 func (apiLang ApiLang) String() string {
 	return string(apiLang)
 }
 
+// IsSourceLang returns whether the language is known to be usable as source language.
+func (apiLang ApiLang) IsSourceLang() bool {
+	return knownLangs[ApiLang(strings.ToUpper(apiLang.String()))]&langUsageSource != 0
+}
+
+// IsTargetLang returns whether the language is known to be usable as target language.
+func (apiLang ApiLang) IsTargetLang() bool {
+	return knownLangs[ApiLang(strings.ToUpper(apiLang.String()))]&langUsageTarget != 0
+}
+
 // LangFromString tries to find and return the matching wrapped API language type.
 func LangFromString(apiLangString string) (error, ApiLang) {
-	switch apiLangString {
-	case "BG":
-		return nil, LangBG
-	case "CS":
-		return nil, LangCS
-	case "DA":
-		return nil, LangDA
-	case "DE":
-		return nil, LangDE
-	case "EL":
-		return nil, LangEL
-	case "EN":
-		return nil, LangEN
-	case "ES":
-		return nil, LangES
-	case "ET":
-		return nil, LangET
-	case "FI":
-		return nil, LangFI
-	case "FR":
-		return nil, LangFR
-	case "HU":
-		return nil, LangHU
-	case "ID":
-		return nil, LangID
-	case "IT":
-		return nil, LangIT
-	case "JA":
-		return nil, LangJA
-	case "LT":
-		return nil, LangLT
-	case "LV":
-		return nil, LangLV
-	case "NL":
-		return nil, LangNL
-	case "PL":
-		return nil, LangPL
-	case "PT":
-		return nil, LangPT
-	case "RO":
-		return nil, LangRO
-	case "RU":
-		return nil, LangRU
-	case "SK":
-		return nil, LangSK
-	case "SL":
-		return nil, LangSL
-	case "SV":
-		return nil, LangSV
-	case "TR":
-		return nil, LangTR
-	case "UK":
-		return nil, LangUK
-	case "ZH":
-		return nil, LangZH
-	default:
+	apiLang := ApiLang(apiLangString)
+	if _, ok := knownLangs[apiLang]; !ok {
 		return fmt.Errorf("could not find API language: %s", apiLangString), ""
 	}
+	return nil, apiLang
+}
+
+// validateLangs checks whether the given languages can be used as source and target language before a request is
+// sent. The source language may be omitted. Languages unknown to this library are accepted if they are cached by the
+// client (see RefreshLanguages) or if no languages are cached at all, as they may have been launched after the
+// release of this library.
+func (client *Client) validateLangs(sourceLang, targetLang ApiLang) error {
+	if len(targetLang) == 0 {
+		return errors.New("'TargetLang' field of translation request cannot be omitted")
+	}
+	if sourceLang != "" && !client.isValidLang(sourceLang, false) {
+		if ApiLang(strings.ToUpper(sourceLang.String())).IsTargetLang() {
+			return fmt.Errorf("%s can only be used as target language, use the language without variant as source "+
+				"language instead", sourceLang)
+		}
+		return fmt.Errorf("%s cannot be used as source language", sourceLang)
+	}
+	if !client.isValidLang(targetLang, true) {
+		if ApiLang(strings.ToUpper(targetLang.String())).IsSourceLang() {
+			return fmt.Errorf("%s can only be used as source language, use one of its variants (e.g. %s-GB or "+
+				"%s-US for English) as target language instead", targetLang, LangEN, LangEN)
+		}
+		return fmt.Errorf("%s cannot be used as target language", targetLang)
+	}
+	return nil
+}
+
+// isValidLang returns whether the language can be used as source or target language.
+func (client *Client) isValidLang(apiLang ApiLang, target bool) bool {
+	apiLang = ApiLang(strings.ToUpper(apiLang.String()))
+	if usage, ok := knownLangs[apiLang]; ok {
+		if target {
+			return usage&langUsageTarget != 0
+		}
+		return usage&langUsageSource != 0
+	}
+	if _, ok := client.languages.lookup(apiLang, target); ok {
+		return true
+	}
+	return !client.languages.cached(target)
 }
 
 // ApiFormality is used to set whether the translation should lean towards formal or informal language.
@@ -269,31 +542,233 @@ const (
 	FormalityLess = ApiFormality("less")
 )
 
-// UsageResponse represents the data of the json response of the usage API function.
+// UsageResponse represents the data of the json response of the usage API function. Depending on the plan, the API
+// server omits some limits, which is reported by the Has...Limit methods. An absent character limit means that the
+// amount of characters is unlimited.
 type UsageResponse struct {
 	// CharacterCount contains the amount of characters translated so far in the current billing period.
 	CharacterCount int64 `json:"character_count"`
 	// CharacterLimit contains the maximum volume of characters that can be translated in the current billing period.
 	CharacterLimit int64 `json:"character_limit"`
+	// DocumentCount contains the amount of documents translated so far in the current billing period.
+	DocumentCount int64 `json:"document_count"`
+	// DocumentLimit contains the maximum amount of documents that can be translated in the current billing period.
+	DocumentLimit int64 `json:"document_limit"`
+	// TeamDocumentCount contains the amount of documents translated by the whole team so far in the current billing
+	// period.
+	TeamDocumentCount int64 `json:"team_document_count"`
+	// TeamDocumentLimit contains the maximum amount of documents that can be translated by the whole team in the
+	// current billing period.
+	TeamDocumentLimit int64 `json:"team_document_limit"`
+
+	// the following fields are set if the corresponding limit has been omitted by the API server
+	characterLimitAbsent    bool
+	documentLimitAbsent     bool
+	teamDocumentLimitAbsent bool
+}
+
+// usageResponseJSON has the same fields as UsageResponse, but without its methods, so it can be decoded by
+// UsageResponse.UnmarshalJSON without recursion.
+type usageResponseJSON UsageResponse
+
+// UnmarshalJSON decodes the usage response and records which limits are absent.
+func (resp *UsageResponse) UnmarshalJSON(data []byte) error {
+	decoded := struct {
+		*usageResponseJSON
+		CharacterLimit    *int64 `json:"character_limit"`
+		DocumentLimit     *int64 `json:"document_limit"`
+		TeamDocumentLimit *int64 `json:"team_document_limit"`
+	}{usageResponseJSON: (*usageResponseJSON)(resp)}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	resp.CharacterLimit, resp.characterLimitAbsent = optionalLimit(decoded.CharacterLimit)
+	resp.DocumentLimit, resp.documentLimitAbsent = optionalLimit(decoded.DocumentLimit)
+	resp.TeamDocumentLimit, resp.teamDocumentLimitAbsent = optionalLimit(decoded.TeamDocumentLimit)
+	return nil
+}
+
+// optionalLimit returns the value of the decoded limit and whether it is absent.
+func optionalLimit(limit *int64) (int64, bool) {
+	if limit == nil {
+		return 0, true
+	}
+	return *limit, false
+}
+
+// MarshalJSON encodes the usage response and omits absent limits, so they are still absent after decoding it again.
+func (resp UsageResponse) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		usageResponseJSON
+		CharacterLimit    *int64 `json:"character_limit,omitempty"`
+		DocumentLimit     *int64 `json:"document_limit,omitempty"`
+		TeamDocumentLimit *int64 `json:"team_document_limit,omitempty"`
+	}{
+		usageResponseJSON: usageResponseJSON(resp),
+		CharacterLimit:    presentLimit(resp.CharacterLimit, resp.characterLimitAbsent),
+		DocumentLimit:     presentLimit(resp.DocumentLimit, resp.documentLimitAbsent),
+		TeamDocumentLimit: presentLimit(resp.TeamDocumentLimit, resp.teamDocumentLimitAbsent),
+	})
+}
+
+// presentLimit returns the limit to be encoded, which is nil if it is absent.
+func presentLimit(limit int64, absent bool) *int64 {
+	if absent {
+		return nil
+	}
+	return &limit
+}
+
+// HasCharacterLimit returns whether the amount of characters is limited. It is false for unlimited plans.
+func (resp *UsageResponse) HasCharacterLimit() bool {
+	return !resp.characterLimitAbsent
+}
+
+// HasDocumentLimit returns whether the amount of documents is limited.
+func (resp *UsageResponse) HasDocumentLimit() bool {
+	return !resp.documentLimitAbsent
+}
+
+// HasTeamDocumentLimit returns whether the amount of documents translated by the whole team is limited.
+func (resp *UsageResponse) HasTeamDocumentLimit() bool {
+	return !resp.teamDocumentLimitAbsent
+}
+
+// Remaining returns the amount of characters which can still be translated in the current billing period. It returns
+// math.MaxInt64 if the amount of characters is unlimited.
+func (resp *UsageResponse) Remaining() int64 {
+	if !resp.HasCharacterLimit() {
+		return math.MaxInt64
+	}
+	return remaining(resp.CharacterCount, resp.CharacterLimit)
+}
+
+// DocumentsRemaining returns the amount of documents which can still be translated in the current billing period,
+// considering the limits of the account and the team. It returns math.MaxInt64 if the amount of documents is
+// unlimited.
+func (resp *UsageResponse) DocumentsRemaining() int64 {
+	documents := int64(math.MaxInt64)
+	if resp.HasDocumentLimit() {
+		documents = remaining(resp.DocumentCount, resp.DocumentLimit)
+	}
+	if resp.HasTeamDocumentLimit() {
+		if teamDocuments := remaining(resp.TeamDocumentCount, resp.TeamDocumentLimit); teamDocuments < documents {
+			documents = teamDocuments
+		}
+	}
+	return documents
+}
+
+// remaining returns the difference between limit and count, which is never negative.
+func remaining(count, limit int64) int64 {
+	if count >= limit {
+		return 0
+	}
+	return limit - count
+}
+
+// PercentUsed returns the percentage (between 0 and 100) of the character limit used so far. It returns 0 if the
+// amount of characters is unlimited.
+func (resp *UsageResponse) PercentUsed() float64 {
+	if !resp.HasCharacterLimit() {
+		return 0
+	}
+	if resp.CharacterLimit <= 0 {
+		return 100
+	}
+	percent := float64(resp.CharacterCount) / float64(resp.CharacterLimit) * 100
+	if percent > 100 {
+		return 100
+	}
+	return percent
+}
+
+// LimitReached returns whether any limit (characters, documents or team documents) has been reached, so further
+// translations of that kind will fail.
+func (resp *UsageResponse) LimitReached() bool {
+	return resp.Remaining() == 0 || resp.DocumentsRemaining() == 0
 }
 
 // GetUsage returns the usage information for the current billing period.
 func (client *Client) GetUsage() (resp *UsageResponse, err error) {
+	return client.GetUsageWithContext(context.Background())
+}
+
+// GetUsageWithContext returns the usage information for the current billing period. The request is cancelled as soon
+// as the given context is done. If the client uses a key pool, the usage of all its keys is summed up unless the
+// context is bound to a single key (see WithPooledKey).
+func (client *Client) GetUsageWithContext(ctx context.Context) (resp *UsageResponse, err error) {
+	ctx, span := client.startSpan(ctx, "deepl.get_usage")
+	defer func() {
+		endSpan(span, err)
+	}()
+	if client.keys == nil || bound(ctx) {
+		resp, err = client.getUsage(ctx)
+	} else {
+		resp, err = client.getPooledUsage(ctx)
+	}
+	if err == nil && client.metrics != nil {
+		client.metrics.ObserveUsage(resp)
+	}
+	return
+}
+
+// getUsage retrieves the usage information of a single auth key.
+func (client *Client) getUsage(ctx context.Context) (resp *UsageResponse, err error) {
 	// execute api function
 	var httpResp *http.Response
-	httpResp, err = client.doApiFunction(usageFunctionUri, http.MethodGet, &url.Values{})
+	httpResp, err = client.doApiFunction(ctx, usageFunctionUri, http.MethodGet, &url.Values{})
 	// check for error
 	if err != nil {
 		return
 	}
 	// parse answer into UsageResponse struct
-	defer func(Body io.ReadCloser) {
-		err := Body.Close()
-		if err != nil {
-			fmt.Printf("could not close response of usage report retrieval request%e\n", err)
-		}
-	}(httpResp.Body)
+	defer client.closeBody(httpResp.Body, "usage report retrieval")
 	resp = &UsageResponse{}
-	err = json.NewDecoder(httpResp.Body).Decode(resp)
+	if err = json.NewDecoder(httpResp.Body).Decode(resp); err != nil {
+		return nil, err
+	}
 	return
 }
+
+// getPooledUsage retrieves the usage information of all keys of the key pool and returns their sum. A limit of the sum
+// is only set if all keys are limited. Keys whose quota has been exceeded or which have been rejected are marked as
+// unavailable and skipped, so an error is only returned if none of the keys reports its usage.
+func (client *Client) getPooledUsage(ctx context.Context) (*UsageResponse, error) {
+	total := &UsageResponse{}
+	var skipErr error
+	reported := false
+	for _, keyId := range client.keys.pooledKeyIds() {
+		resp, err := client.getUsage(WithPooledKey(ctx, keyId))
+		if err != nil {
+			err = fmt.Errorf("could not retrieve usage of auth key %s: %w", keyId, err)
+			if !client.keys.markUnavailable(client.keys.keyById(keyId), err) {
+				return nil, err
+			}
+			skipErr = err
+			continue
+		}
+		reported = true
+		total.CharacterCount += resp.CharacterCount
+		total.DocumentCount += resp.DocumentCount
+		total.TeamDocumentCount += resp.TeamDocumentCount
+		total.CharacterLimit, total.characterLimitAbsent = addLimit(total.CharacterLimit, total.characterLimitAbsent,
+			resp.CharacterLimit, resp.characterLimitAbsent)
+		total.DocumentLimit, total.documentLimitAbsent = addLimit(total.DocumentLimit, total.documentLimitAbsent,
+			resp.DocumentLimit, resp.documentLimitAbsent)
+		total.TeamDocumentLimit, total.teamDocumentLimitAbsent = addLimit(total.TeamDocumentLimit,
+			total.teamDocumentLimitAbsent, resp.TeamDocumentLimit, resp.teamDocumentLimitAbsent)
+	}
+	if !reported {
+		return nil, skipErr
+	}
+	return total, nil
+}
+
+// addLimit returns the sum of both limits, which is absent (unlimited) if any of them is absent.
+func addLimit(limit int64, absent bool, other int64, otherAbsent bool) (int64, bool) {
+	if absent || otherAbsent {
+		return 0, true
+	}
+	return limit + other, false
+}
@@ -1,23 +1,48 @@
 package deeplclient
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/PineiroHosting/deeplgobindings/pkg/deepltest"
+	"math"
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"testing"
+	"time"
 )
 
 const endpointUrl = "https://api-free.deepl.com/v2/"
 
-func TestGetUsage(t *testing.T) {
-	authKey := os.Getenv("DEEPL_TEST_AUTH_KEY")
-	if authKey == "" {
-		t.Fatal("could not find 'DEEPL_TEST_AUTH_KEY' environment variable")
+// newTestClient creates a client for the real DeepL API if the environment variable DEEPL_TEST_AUTH_KEY is set.
+// Otherwise, the client uses a local fake server.
+func newTestClient(t *testing.T) *Client {
+	if authKey := os.Getenv("DEEPL_TEST_AUTH_KEY"); authKey != "" {
+		return &Client{
+			Client:      &http.Client{},
+			AuthKey:     []byte(authKey),
+			EndpointUrl: endpointUrl,
+		}
 	}
-	client := &Client{
-		Client:      &http.Client{},
-		AuthKey:     []byte(authKey),
-		EndpointUrl: endpointUrl,
+	client, _ := newFakeClient(t)
+	return client
+}
+
+// newFakeClient creates a client with the given options which uses a new local fake server.
+func newFakeClient(t *testing.T, options ...Option) (*Client, *deepltest.Server) {
+	server := deepltest.NewServer()
+	t.Cleanup(server.Close)
+	options = append([]Option{WithEndpoint(server.EndpointUrl()), WithHTTPClient(server.Client())}, options...)
+	client, err := NewClient("test", options...)
+	if err != nil {
+		t.Fatal(err)
 	}
+	return client, server
+}
+
+func TestGetUsage(t *testing.T) {
+	client := newTestClient(t)
 	if resp, err := client.GetUsage(); err != nil {
 		t.Fatal(err)
 	} else {
@@ -30,3 +55,134 @@ func TestGetUsage(t *testing.T) {
 		}
 	}
 }
+
+// TestGetUsageWithContextCancel tests whether a cancelled context aborts a pending API request.
+func TestGetUsageWithContextCancel(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+	client := &Client{
+		Client:      &http.Client{},
+		AuthKey:     []byte("test"),
+		EndpointUrl: server.URL + "/v2/",
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := client.GetUsageWithContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded error, got: %v", err)
+	}
+}
+
+// TestValidateLangs tests whether illegal combinations of source and target languages are rejected.
+func TestValidateLangs(t *testing.T) {
+	client := &Client{}
+	tests := []struct {
+		sourceLang ApiLang
+		targetLang ApiLang
+		valid      bool
+	}{
+		{"", LangENUS, true},
+		{LangDE, LangENGB, true},
+		{LangEN, LangPTBR, true},
+		{LangKO, LangZHHANT, true},
+		{"", "", false},
+		{LangDE, LangEN, false},
+		{LangDE, LangPT, false},
+		{LangENUS, LangDE, false},
+		{LangZHHANS, LangDE, false},
+		// languages launched after the release of this library are accepted
+		{"XY", "XY", true},
+	}
+	for _, test := range tests {
+		if err := client.validateLangs(test.sourceLang, test.targetLang); (err == nil) != test.valid {
+			t.Errorf("%q -> %q: expected valid=%t, got error: %v", test.sourceLang, test.targetLang, test.valid, err)
+		}
+	}
+	client.languages.store(languageTypeTarget, []Language{{Code: "XY"}})
+	if err := client.validateLangs(LangDE, "XZ"); err == nil {
+		t.Error("language missing in cached target languages has been accepted")
+	}
+}
+
+// TestUsageResponseLimits tests the decoding of all counters and the handling of absent limits.
+func TestUsageResponseLimits(t *testing.T) {
+	client, server := newFakeClient(t)
+	server.SetUsage(400, 1000)
+	server.SetDocumentUsage(3, 10, 9, 10)
+	resp, err := client.GetUsage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.DocumentCount != 3 || resp.DocumentLimit != 10 || resp.TeamDocumentCount != 9 ||
+		resp.TeamDocumentLimit != 10 {
+		t.Fatalf("unexpected usage response: %+v", resp)
+	}
+	if !resp.HasCharacterLimit() || resp.Remaining() != 600 || resp.PercentUsed() != 40 {
+		t.Fatalf("unexpected character usage: %+v", resp)
+	}
+	if resp.DocumentsRemaining() != 1 || resp.LimitReached() {
+		t.Fatalf("unexpected document usage: %+v", resp)
+	}
+
+	// the last document reaches the team limit
+	if _, err = client.StartDocumentTranslate(&DocumentTranslationStartRequest{
+		TargetLang: LangDE,
+		File:       []byte("Hello world!"),
+		Filename:   "hello.txt",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if resp, err = client.GetUsage(); err != nil {
+		t.Fatal(err)
+	}
+	if resp.DocumentsRemaining() != 0 || !resp.LimitReached() {
+		t.Fatalf("expected document limit to be reached: %+v", resp)
+	}
+
+	// limits are omitted for unlimited plans
+	server.SetUsage(400, 0)
+	server.SetDocumentUsage(0, 0, 0, 0)
+	if resp, err = client.GetUsage(); err != nil {
+		t.Fatal(err)
+	}
+	if resp.HasCharacterLimit() || resp.HasDocumentLimit() || resp.HasTeamDocumentLimit() {
+		t.Fatalf("expected absent limits: %+v", resp)
+	}
+	if resp.Remaining() != math.MaxInt64 || resp.PercentUsed() != 0 || resp.LimitReached() {
+		t.Fatalf("unexpected usage of unlimited plan: %+v", resp)
+	}
+}
+
+// TestUsageResponseJSON tests whether absent limits survive encoding the usage response as JSON and decoding it again.
+func TestUsageResponseJSON(t *testing.T) {
+	unlimited := &UsageResponse{}
+	if err := json.Unmarshal([]byte(`{"character_count":5}`), unlimited); err != nil {
+		t.Fatal(err)
+	}
+	for _, usage := range []*UsageResponse{
+		unlimited,
+		{CharacterCount: 5, CharacterLimit: 1000},
+		{CharacterCount: 5, CharacterLimit: 1000, DocumentCount: 2, DocumentLimit: 1000},
+	} {
+		data, err := json.Marshal(usage)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded := &UsageResponse{}
+		if err = json.Unmarshal(data, decoded); err != nil {
+			t.Fatal(err)
+		}
+		if *decoded != *usage {
+			t.Fatalf("usage response changed by JSON round-trip: %s", data)
+		}
+	}
+	if unlimited.HasCharacterLimit() || unlimited.Remaining() != math.MaxInt64 {
+		t.Fatalf("unexpected usage of unlimited plan: %+v", unlimited)
+	}
+}
@@ -0,0 +1,3 @@
+// Package deepltest provides a local fake DeepL API server for testing code which depends on the deeplclient package
+// without access to the real DeepL API.
+package deepltest
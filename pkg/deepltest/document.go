@@ -0,0 +1,165 @@
+package deepltest
+
+import (
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"unicode/utf8"
+)
+
+// document states as reported by the document translation status API function
+const (
+	documentStatusQueued      = "queued"
+	documentStatusTranslating = "translating"
+	documentStatusDone        = "done"
+	documentStatusError       = "error"
+)
+
+// supportedDocumentExtensions contains the file extensions accepted by the document translation API function.
+var supportedDocumentExtensions = map[string]bool{
+	".docx": true, ".pptx": true, ".xlsx": true, ".pdf": true, ".htm": true, ".html": true, ".txt": true,
+	".xlf": true, ".xliff": true, ".srt": true,
+}
+
+// document contains the state of an uploaded document.
+type document struct {
+	id               string
+	key              string
+	sourceLang       string
+	targetLang       string
+	content          []byte
+	status           string
+	errorMessage     string
+	billedCharacters int64
+	downloaded       bool
+}
+
+// SetDocumentError lets the translation of all documents uploaded afterwards fail with the given error message. An
+// empty message disables the error.
+func (server *Server) SetDocumentError(message string) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+	server.documentError = message
+}
+
+// serveDocument implements the document API functions.
+func (server *Server) serveDocument(w http.ResponseWriter, r *http.Request, subPath string) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	if subPath == "" {
+		server.serveDocumentUpload(w, r)
+		return
+	}
+	documentId, function := subPath, ""
+	if i := strings.Index(subPath, "/"); i >= 0 {
+		documentId, function = subPath[:i], subPath[i+1:]
+	}
+	server.mutex.Lock()
+	doc, ok := server.documents[documentId]
+	server.mutex.Unlock()
+	if !ok || doc.key != r.Form.Get("document_key") {
+		writeError(w, http.StatusNotFound, "Document not found")
+		return
+	}
+	switch function {
+	case "":
+		server.serveDocumentStatus(w, doc)
+	case "result":
+		server.serveDocumentResult(w, doc)
+	default:
+		writeError(w, http.StatusNotFound, "Not found")
+	}
+}
+
+// serveDocumentUpload implements the document translation API function.
+func (server *Server) serveDocumentUpload(w http.ResponseWriter, r *http.Request) {
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Parameter 'file' not specified.")
+		return
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+	if !supportedDocumentExtensions[strings.ToLower(path.Ext(header.Filename))] {
+		writeError(w, http.StatusBadRequest, "Invalid file data.")
+		return
+	}
+	targetLang := strings.ToUpper(r.Form.Get("target_lang"))
+	if targetLang == "" {
+		writeError(w, http.StatusBadRequest, "Value for 'target_lang' not supported.")
+		return
+	}
+	content, err := io.ReadAll(file)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+	if !server.billDocument() {
+		writeError(w, StatusQuotaExceeded, "Quota Exceeded")
+		return
+	}
+	doc := &document{
+		id:           server.nextIdentifier(),
+		key:          server.nextIdentifier(),
+		sourceLang:   strings.ToUpper(r.Form.Get("source_lang")),
+		targetLang:   targetLang,
+		content:      content,
+		status:       documentStatusQueued,
+		errorMessage: server.documentError,
+	}
+	server.documents[doc.id] = doc
+	writeJSON(w, http.StatusOK, map[string]string{"document_id": doc.id, "document_key": doc.key})
+}
+
+// serveDocumentStatus reports the current status of the document and advances its translation by one state, so
+// every document is reported as queued, translating and finally done (or failed).
+func (server *Server) serveDocumentStatus(w http.ResponseWriter, doc *document) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+	status := map[string]interface{}{"document_id": doc.id, "status": doc.status}
+	switch doc.status {
+	case documentStatusQueued:
+		doc.status = documentStatusTranslating
+	case documentStatusTranslating:
+		status["seconds_remaining"] = 1
+		switch {
+		case doc.errorMessage != "":
+			doc.status = documentStatusError
+		case !server.bill(int64(utf8.RuneCount(doc.content))):
+			doc.status, doc.errorMessage = documentStatusError, "Quota exceeded"
+		default:
+			doc.status = documentStatusDone
+			doc.billedCharacters = int64(utf8.RuneCount(doc.content))
+		}
+	case documentStatusDone:
+		status["billed_characters"] = doc.billedCharacters
+	case documentStatusError:
+		status["error_message"] = doc.errorMessage
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+// serveDocumentResult returns the translated document, which can only be downloaded once. The translate function is
+// called without holding the mutex.
+func (server *Server) serveDocumentResult(w http.ResponseWriter, doc *document) {
+	server.mutex.Lock()
+	status, downloaded, translate := doc.status, doc.downloaded, server.translate
+	doc.downloaded = doc.downloaded || status == documentStatusDone
+	server.mutex.Unlock()
+	if status != documentStatusDone {
+		writeError(w, http.StatusServiceUnavailable, "Document translation is not finished yet")
+		return
+	}
+	if downloaded {
+		writeError(w, http.StatusNotFound, "Document has already been downloaded")
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	_, _ = w.Write([]byte(translate(string(doc.content), doc.sourceLang, doc.targetLang)))
+}
@@ -0,0 +1,159 @@
+package deepltest
+
+import (
+	"encoding/csv"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// glossaryLanguagePairs contains the language pairs supported by glossaries of the fake server.
+var glossaryLanguagePairs = [][2]string{
+	{"de", "en"}, {"de", "fr"}, {"en", "de"}, {"en", "es"}, {"en", "fr"}, {"en", "ja"}, {"es", "en"}, {"fr", "de"},
+	{"fr", "en"}, {"ja", "en"},
+}
+
+// glossary contains the information and entries of a created glossary.
+type glossary struct {
+	GlossaryId   string    `json:"glossary_id"`
+	Name         string    `json:"name"`
+	Ready        bool      `json:"ready"`
+	SourceLang   string    `json:"source_lang"`
+	TargetLang   string    `json:"target_lang"`
+	CreationTime time.Time `json:"creation_time"`
+	EntryCount   int       `json:"entry_count"`
+	entries      map[string]string
+}
+
+// serveGlossaries implements the glossary API functions.
+func (server *Server) serveGlossaries(w http.ResponseWriter, r *http.Request, subPath string) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+	if subPath == "" {
+		switch r.Method {
+		case http.MethodPost:
+			server.serveGlossaryCreation(w, r)
+		case http.MethodGet:
+			glossaries := make([]*glossary, 0, len(server.glossaries))
+			for _, g := range server.glossaries {
+				glossaries = append(glossaries, g)
+			}
+			sort.Slice(glossaries, func(i, j int) bool {
+				return glossaries[i].GlossaryId < glossaries[j].GlossaryId
+			})
+			writeJSON(w, http.StatusOK, map[string]interface{}{"glossaries": glossaries})
+		default:
+			writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		}
+		return
+	}
+	glossaryId, function := subPath, ""
+	if i := strings.Index(subPath, "/"); i >= 0 {
+		glossaryId, function = subPath[:i], subPath[i+1:]
+	}
+	g, ok := server.glossaries[glossaryId]
+	if !ok {
+		writeError(w, http.StatusNotFound, "Glossary not found")
+		return
+	}
+	switch {
+	case function == "" && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, g)
+	case function == "" && r.Method == http.MethodDelete:
+		delete(server.glossaries, glossaryId)
+		w.WriteHeader(http.StatusNoContent)
+	case function == "entries" && r.Method == http.MethodGet:
+		accept := r.Header.Get("Accept")
+		if accept != "" && accept != "*/*" && accept != "text/tab-separated-values" {
+			writeError(w, http.StatusUnsupportedMediaType, "Unsupported accept header")
+			return
+		}
+		sourceTerms := make([]string, 0, len(g.entries))
+		for source := range g.entries {
+			sourceTerms = append(sourceTerms, source)
+		}
+		sort.Strings(sourceTerms)
+		lines := make([]string, len(sourceTerms))
+		for i, source := range sourceTerms {
+			lines[i] = source + "\t" + g.entries[source]
+		}
+		w.Header().Set("Content-Type", "text/tab-separated-values")
+		_, _ = w.Write([]byte(strings.Join(lines, "\n")))
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// serveGlossaryCreation implements the glossary creation API function. The caller must hold the mutex.
+func (server *Server) serveGlossaryCreation(w http.ResponseWriter, r *http.Request) {
+	name := r.Form.Get("name")
+	sourceLang, targetLang := strings.ToLower(r.Form.Get("source_lang")), strings.ToLower(r.Form.Get("target_lang"))
+	if name == "" {
+		writeError(w, http.StatusBadRequest, "Parameter 'name' not specified.")
+		return
+	}
+	supported := false
+	for _, pair := range glossaryLanguagePairs {
+		supported = supported || (pair[0] == sourceLang && pair[1] == targetLang)
+	}
+	if !supported {
+		writeError(w, http.StatusBadRequest, "Unsupported glossary source and target language pair.")
+		return
+	}
+	entries := map[string]string{}
+	switch r.Form.Get("entries_format") {
+	case "tsv":
+		for _, line := range strings.Split(r.Form.Get("entries"), "\n") {
+			line = strings.TrimSuffix(line, "\r")
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+			terms := strings.Split(line, "\t")
+			if len(terms) != 2 {
+				writeError(w, http.StatusBadRequest, "Invalid glossary entries provided")
+				return
+			}
+			entries[terms[0]] = terms[1]
+		}
+	case "csv":
+		reader := csv.NewReader(strings.NewReader(r.Form.Get("entries")))
+		reader.FieldsPerRecord = 2
+		records, err := reader.ReadAll()
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "Invalid glossary entries provided")
+			return
+		}
+		for _, record := range records {
+			entries[record[0]] = record[1]
+		}
+	default:
+		writeError(w, http.StatusBadRequest, "Value for 'entries_format' not supported.")
+		return
+	}
+	if len(entries) == 0 {
+		writeError(w, http.StatusBadRequest, "Invalid glossary entries provided")
+		return
+	}
+	g := &glossary{
+		GlossaryId:   strings.ToLower(server.nextIdentifier()),
+		Name:         name,
+		Ready:        true,
+		SourceLang:   sourceLang,
+		TargetLang:   targetLang,
+		CreationTime: time.Now().UTC(),
+		EntryCount:   len(entries),
+		entries:      entries,
+	}
+	server.glossaries[g.GlossaryId] = g
+	writeJSON(w, http.StatusCreated, g)
+}
+
+// serveGlossaryLanguagePairs implements the glossary language pairs API function.
+func serveGlossaryLanguagePairs(w http.ResponseWriter, r *http.Request) {
+	pairs := make([]map[string]string, len(glossaryLanguagePairs))
+	for i, pair := range glossaryLanguagePairs {
+		pairs[i] = map[string]string{"source_lang": pair[0], "target_lang": pair[1]}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"supported_languages": pairs})
+}
@@ -0,0 +1,58 @@
+package deepltest
+
+import (
+	"net/http"
+)
+
+// language describes a language supported by the fake server.
+type language struct {
+	Language          string `json:"language"`
+	Name              string `json:"name"`
+	SupportsFormality bool   `json:"supports_formality"`
+}
+
+// sourceLanguages contains all languages which can be used as source language.
+var sourceLanguages = []language{
+	{Language: "AR", Name: "Arabic"}, {Language: "BG", Name: "Bulgarian"}, {Language: "CS", Name: "Czech"},
+	{Language: "DA", Name: "Danish"}, {Language: "DE", Name: "German"}, {Language: "EL", Name: "Greek"},
+	{Language: "EN", Name: "English"}, {Language: "ES", Name: "Spanish"}, {Language: "ET", Name: "Estonian"},
+	{Language: "FI", Name: "Finnish"}, {Language: "FR", Name: "French"}, {Language: "HU", Name: "Hungarian"},
+	{Language: "ID", Name: "Indonesian"}, {Language: "IT", Name: "Italian"}, {Language: "JA", Name: "Japanese"},
+	{Language: "KO", Name: "Korean"}, {Language: "LT", Name: "Lithuanian"}, {Language: "LV", Name: "Latvian"},
+	{Language: "NB", Name: "Norwegian"}, {Language: "NL", Name: "Dutch"}, {Language: "PL", Name: "Polish"},
+	{Language: "PT", Name: "Portuguese"}, {Language: "RO", Name: "Romanian"}, {Language: "RU", Name: "Russian"},
+	{Language: "SK", Name: "Slovak"}, {Language: "SL", Name: "Slovenian"}, {Language: "SV", Name: "Swedish"},
+	{Language: "TR", Name: "Turkish"}, {Language: "UK", Name: "Ukrainian"}, {Language: "ZH", Name: "Chinese"},
+}
+
+// targetLanguages contains all languages which can be used as target language.
+var targetLanguages = []language{
+	{Language: "AR", Name: "Arabic"}, {Language: "BG", Name: "Bulgarian"}, {Language: "CS", Name: "Czech"},
+	{Language: "DA", Name: "Danish"}, {Language: "DE", Name: "German", SupportsFormality: true},
+	{Language: "EL", Name: "Greek"}, {Language: "EN-GB", Name: "English (British)"},
+	{Language: "EN-US", Name: "English (American)"}, {Language: "ES", Name: "Spanish", SupportsFormality: true},
+	{Language: "ET", Name: "Estonian"}, {Language: "FI", Name: "Finnish"},
+	{Language: "FR", Name: "French", SupportsFormality: true}, {Language: "HU", Name: "Hungarian"},
+	{Language: "ID", Name: "Indonesian"}, {Language: "IT", Name: "Italian", SupportsFormality: true},
+	{Language: "JA", Name: "Japanese", SupportsFormality: true}, {Language: "KO", Name: "Korean"},
+	{Language: "LT", Name: "Lithuanian"}, {Language: "LV", Name: "Latvian"}, {Language: "NB", Name: "Norwegian"},
+	{Language: "NL", Name: "Dutch", SupportsFormality: true}, {Language: "PL", Name: "Polish", SupportsFormality: true},
+	{Language: "PT-BR", Name: "Portuguese (Brazilian)", SupportsFormality: true},
+	{Language: "PT-PT", Name: "Portuguese (European)", SupportsFormality: true}, {Language: "RO", Name: "Romanian"},
+	{Language: "RU", Name: "Russian", SupportsFormality: true}, {Language: "SK", Name: "Slovak"},
+	{Language: "SL", Name: "Slovenian"}, {Language: "SV", Name: "Swedish"}, {Language: "TR", Name: "Turkish"},
+	{Language: "UK", Name: "Ukrainian"}, {Language: "ZH", Name: "Chinese (simplified)"},
+	{Language: "ZH-HANS", Name: "Chinese (simplified)"}, {Language: "ZH-HANT", Name: "Chinese (traditional)"},
+}
+
+// serveLanguages implements the languages API function.
+func serveLanguages(w http.ResponseWriter, r *http.Request) {
+	switch r.Form.Get("type") {
+	case "", "source":
+		writeJSON(w, http.StatusOK, sourceLanguages)
+	case "target":
+		writeJSON(w, http.StatusOK, targetLanguages)
+	default:
+		writeError(w, http.StatusBadRequest, "Value for 'type' not supported.")
+	}
+}
@@ -0,0 +1,386 @@
+package deepltest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+const (
+	// ApiPathPrefix is the path prefix of all API functions served by the fake server.
+	ApiPathPrefix = "/v2/"
+	// StatusQuotaExceeded is the unofficial internal HTTP status code for "Quota exceeded"
+	StatusQuotaExceeded = 456
+	// DefaultCharacterLimit is the character limit of a new fake server.
+	DefaultCharacterLimit = 500000
+	// DefaultDetectedSourceLang is the language detected by a new fake server if the source language is omitted.
+	DefaultDetectedSourceLang = "EN"
+)
+
+// TranslateFunc "translates" a text deterministically.
+type TranslateFunc func(text, sourceLang, targetLang string) string
+
+// DefaultTranslate prefixes the text with the target language in brackets, e.g. "[DE] Hello world!".
+func DefaultTranslate(text, sourceLang, targetLang string) string {
+	return "[" + targetLang + "] " + text
+}
+
+// Request contains the information about a request received by the fake server.
+type Request struct {
+	// Method is the HTTP method of the request.
+	Method string
+	// Path is the path of the request without the API path prefix, e.g. "translate".
+	Path string
+	// Header contains the headers of the request.
+	Header http.Header
+	// Form contains the parsed query parameters and the url encoded or multipart form values.
+	Form url.Values
+	// Body is the raw body of the request.
+	Body []byte
+}
+
+// ErrorInjection describes an error the fake server responds with instead of handling requests.
+type ErrorInjection struct {
+	// Path restricts the injection to requests whose path (without API path prefix) starts with the given path. An
+	// empty path matches all requests.
+	Path string
+	// StatusCode is the HTTP status code of the response.
+	StatusCode int
+	// Count is the amount of requests which fail. Zero or negative values let all matching requests fail until the
+	// injections are cleared.
+	Count int
+	// Message is returned as error message. Defaults to the status text.
+	Message string
+	// Header contains additional headers of the response, e.g. "Retry-After".
+	Header http.Header
+}
+
+// Server is a fake DeepL API server based on httptest.Server. It implements the translate, usage, document, glossary
+// and language API functions with deterministic fake translations. All methods are safe for concurrent use.
+type Server struct {
+	*httptest.Server
+
+	mutex              sync.Mutex
+	authKey            string
+	translate          TranslateFunc
+	detectedSourceLang string
+	characterCount     int64
+	characterLimit     int64
+	documentCount      int64
+	documentLimit      int64
+	teamDocumentCount  int64
+	teamDocumentLimit  int64
+	requests           []Request
+	injections         []*ErrorInjection
+	documents          map[string]*document
+	documentError      string
+	glossaries         map[string]*glossary
+	nextId             int
+}
+
+// NewServer starts and returns a new fake server. The caller should call Close when finished.
+func NewServer() *Server {
+	server := &Server{
+		translate:          DefaultTranslate,
+		detectedSourceLang: DefaultDetectedSourceLang,
+		characterLimit:     DefaultCharacterLimit,
+		documents:          map[string]*document{},
+		glossaries:         map[string]*glossary{},
+	}
+	server.Server = httptest.NewServer(http.HandlerFunc(server.serveHTTP))
+	return server
+}
+
+// EndpointUrl returns the url of the fake API, which can be used as endpoint url of a client.
+func (server *Server) EndpointUrl() string {
+	return server.URL + ApiPathPrefix
+}
+
+// SetAuthKey restricts the access to requests with the given auth key. By default, every auth key is accepted.
+func (server *Server) SetAuthKey(authKey string) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+	server.authKey = authKey
+}
+
+// SetTranslateFunc replaces the function used to "translate" texts and documents.
+func (server *Server) SetTranslateFunc(translate TranslateFunc) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+	server.translate = translate
+}
+
+// SetDetectedSourceLang sets the language reported as detected if the source language is omitted.
+func (server *Server) SetDetectedSourceLang(sourceLang string) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+	server.detectedSourceLang = sourceLang
+}
+
+// SetUsage sets the amount of characters translated so far and the character limit. Translations exceeding the limit
+// fail with status code 456. A limit of zero means unlimited, so the limit is omitted in the usage response.
+func (server *Server) SetUsage(characterCount, characterLimit int64) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+	server.characterCount = characterCount
+	server.characterLimit = characterLimit
+}
+
+// SetDocumentUsage sets the amount of documents translated so far and the document limits of the account and the
+// team. Every uploaded document is counted for both, and uploads exceeding a limit fail with status code 456. Limits
+// of zero are omitted in the usage response, which is the default.
+func (server *Server) SetDocumentUsage(documentCount, documentLimit, teamDocumentCount, teamDocumentLimit int64) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+	server.documentCount = documentCount
+	server.documentLimit = documentLimit
+	server.teamDocumentCount = teamDocumentCount
+	server.teamDocumentLimit = teamDocumentLimit
+}
+
+// CharacterCount returns the amount of characters translated so far.
+func (server *Server) CharacterCount() int64 {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+	return server.characterCount
+}
+
+// InjectError lets the server respond to matching requests with the described error. Injections are matched in the
+// order they have been added.
+func (server *Server) InjectError(injection ErrorInjection) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+	server.injections = append(server.injections, &injection)
+}
+
+// ClearErrors removes all error injections.
+func (server *Server) ClearErrors() {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+	server.injections = nil
+}
+
+// Requests returns all requests received so far.
+func (server *Server) Requests() []Request {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+	return append([]Request(nil), server.requests...)
+}
+
+// ResetRequests forgets all requests received so far.
+func (server *Server) ResetRequests() {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+	server.requests = nil
+}
+
+// serveHTTP records the request and dispatches it to the matching API function.
+func (server *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		err = r.ParseMultipartForm(32 << 20)
+	} else {
+		err = r.ParseForm()
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	path := strings.TrimPrefix(r.URL.Path, ApiPathPrefix)
+
+	// the mutex is only held while the state is accessed, so requests are served concurrently
+	server.mutex.Lock()
+	server.requests = append(server.requests, Request{
+		Method: r.Method,
+		Path:   path,
+		Header: r.Header.Clone(),
+		Form:   r.Form,
+		Body:   body,
+	})
+	authKey := strings.TrimPrefix(r.Header.Get("Authorization"), "DeepL-Auth-Key ")
+	authorized := authKey != "" && authKey != r.Header.Get("Authorization") &&
+		(server.authKey == "" || authKey == server.authKey)
+	var injection *ErrorInjection
+	if authorized {
+		injection = server.matchInjection(path)
+	}
+	server.mutex.Unlock()
+
+	switch {
+	case !strings.HasPrefix(r.URL.Path, ApiPathPrefix):
+		writeError(w, http.StatusNotFound, "Not found")
+		return
+	case !authorized:
+		writeError(w, http.StatusForbidden, "Authorization failed")
+		return
+	case injection != nil:
+		writeInjection(w, injection)
+		return
+	}
+
+	switch {
+	case path == "translate":
+		server.serveTranslate(w, r)
+	case path == "usage":
+		server.serveUsage(w)
+	case path == "languages":
+		serveLanguages(w, r)
+	case path == "document" || strings.HasPrefix(path, "document/"):
+		server.serveDocument(w, r, strings.TrimPrefix(strings.TrimPrefix(path, "document"), "/"))
+	case path == "glossaries" || strings.HasPrefix(path, "glossaries/"):
+		server.serveGlossaries(w, r, strings.TrimPrefix(strings.TrimPrefix(path, "glossaries"), "/"))
+	case path == "glossary-language-pairs":
+		serveGlossaryLanguagePairs(w, r)
+	default:
+		writeError(w, http.StatusNotFound, "Not found")
+	}
+}
+
+// matchInjection returns the first injected error matching the path, if any, and counts its usage. The caller must
+// hold the mutex.
+func (server *Server) matchInjection(path string) *ErrorInjection {
+	for i, injection := range server.injections {
+		if !strings.HasPrefix(path, injection.Path) {
+			continue
+		}
+		if injection.Count > 0 {
+			if injection.Count--; injection.Count == 0 {
+				server.injections = append(server.injections[:i], server.injections[i+1:]...)
+			}
+		}
+		return injection
+	}
+	return nil
+}
+
+// writeInjection writes the injected error.
+func writeInjection(w http.ResponseWriter, injection *ErrorInjection) {
+	for key, values := range injection.Header {
+		for _, value := range values {
+			w.Header().Add(key, value)
+		}
+	}
+	message := injection.Message
+	if message == "" {
+		message = http.StatusText(injection.StatusCode)
+	}
+	writeError(w, injection.StatusCode, message)
+}
+
+// serveUsage implements the usage API function. Only the configured limits are reported.
+func (server *Server) serveUsage(w http.ResponseWriter) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+	usage := map[string]int64{"character_count": server.characterCount}
+	if server.characterLimit > 0 {
+		usage["character_limit"] = server.characterLimit
+	}
+	if server.documentLimit > 0 {
+		usage["document_count"] = server.documentCount
+		usage["document_limit"] = server.documentLimit
+	}
+	if server.teamDocumentLimit > 0 {
+		usage["team_document_count"] = server.teamDocumentCount
+		usage["team_document_limit"] = server.teamDocumentLimit
+	}
+	writeJSON(w, http.StatusOK, usage)
+}
+
+// billDocument adds a document to the document counts. It returns false if a document limit would be exceeded. The
+// caller must hold the mutex.
+func (server *Server) billDocument() bool {
+	if (server.documentLimit > 0 && server.documentCount >= server.documentLimit) ||
+		(server.teamDocumentLimit > 0 && server.teamDocumentCount >= server.teamDocumentLimit) {
+		return false
+	}
+	server.documentCount++
+	server.teamDocumentCount++
+	return true
+}
+
+// bill adds the given amount of characters to the character count. It returns false if the character limit would be
+// exceeded. The caller must hold the mutex.
+func (server *Server) bill(characters int64) bool {
+	if server.characterLimit > 0 && server.characterCount+characters > server.characterLimit {
+		return false
+	}
+	server.characterCount += characters
+	return true
+}
+
+// serveTranslate implements the translate API function. The translate function is called without holding the mutex,
+// so it may call any method of the server.
+func (server *Server) serveTranslate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost && r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	texts := r.Form["text"]
+	targetLang := strings.ToUpper(r.Form.Get("target_lang"))
+	if len(texts) == 0 {
+		writeError(w, http.StatusBadRequest, "Parameter 'text' not specified.")
+		return
+	}
+	if targetLang == "" {
+		writeError(w, http.StatusBadRequest, "Value for 'target_lang' not supported.")
+		return
+	}
+	var characters int64
+	for _, text := range texts {
+		characters += int64(utf8.RuneCountInString(text))
+	}
+	server.mutex.Lock()
+	billed := server.bill(characters)
+	translate, detectedSourceLang := server.translate, server.detectedSourceLang
+	server.mutex.Unlock()
+	if !billed {
+		writeError(w, StatusQuotaExceeded, "Quota Exceeded")
+		return
+	}
+	sourceLang := strings.ToUpper(r.Form.Get("source_lang"))
+	if sourceLang != "" {
+		detectedSourceLang = sourceLang
+	}
+	type translation struct {
+		DetectedSourceLanguage string `json:"detected_source_language"`
+		Text                   string `json:"text"`
+	}
+	translations := make([]translation, len(texts))
+	for i, text := range texts {
+		translations[i] = translation{
+			DetectedSourceLanguage: detectedSourceLang,
+			Text:                   translate(text, sourceLang, targetLang),
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"translations": translations})
+}
+
+// nextIdentifier returns a new unique hexadecimal ID. The caller must hold the mutex.
+func (server *Server) nextIdentifier() string {
+	server.nextId++
+	return fmt.Sprintf("%032X", server.nextId)
+}
+
+// writeJSON writes the value as JSON response with the given status code.
+func writeJSON(w http.ResponseWriter, statusCode int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(value)
+}
+
+// writeError writes an error response in the format used by the DeepL API.
+func writeError(w http.ResponseWriter, statusCode int, message string) {
+	writeJSON(w, statusCode, map[string]string{"message": message})
+}
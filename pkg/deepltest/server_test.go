@@ -0,0 +1,165 @@
+package deepltest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// post sends an url encoded form to the given API function of the server.
+func post(t *testing.T, server *Server, function, authKey string, values url.Values) *http.Response {
+	req, err := http.NewRequest(http.MethodPost, server.EndpointUrl()+function, strings.NewReader(values.Encode()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "DeepL-Auth-Key "+authKey)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = resp.Body.Close()
+	})
+	return resp
+}
+
+// TestServerTranslate tests the fake translations and the accounting of characters.
+func TestServerTranslate(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	server.SetUsage(0, 20)
+	resp := post(t, server, "translate", "key", url.Values{"text": {"Hallo", "Welt"}, "target_lang": {"EN-GB"}})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected status code %d", resp.StatusCode)
+	}
+	result := &struct {
+		Translations []struct {
+			DetectedSourceLanguage string `json:"detected_source_language"`
+			Text                   string `json:"text"`
+		} `json:"translations"`
+	}{}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Translations) != 2 || result.Translations[1].Text != "[EN-GB] Welt" ||
+		result.Translations[1].DetectedSourceLanguage != DefaultDetectedSourceLang {
+		t.Fatalf("unexpected translations: %+v", result.Translations)
+	}
+	if server.CharacterCount() != 9 {
+		t.Fatalf("expected 9 characters to be billed, got %d", server.CharacterCount())
+	}
+	resp = post(t, server, "translate", "key", url.Values{"text": {"Hallo Welt, hallo!"}, "target_lang": {"DE"}})
+	if resp.StatusCode != StatusQuotaExceeded {
+		t.Fatalf("expected exceeded quota, got status code %d", resp.StatusCode)
+	}
+}
+
+// TestServerErrorInjection tests whether injected errors are returned for matching requests only.
+func TestServerErrorInjection(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	server.InjectError(ErrorInjection{
+		Path:       "translate",
+		StatusCode: http.StatusTooManyRequests,
+		Count:      2,
+		Header:     http.Header{"Retry-After": {"1"}},
+	})
+	values := url.Values{"text": {"Hallo"}, "target_lang": {"DE"}}
+	if resp := post(t, server, "usage", "key", nil); resp.StatusCode != http.StatusOK {
+		t.Fatalf("error has been injected into non-matching request: %d", resp.StatusCode)
+	}
+	for i := 0; i < 2; i++ {
+		resp := post(t, server, "translate", "key", values)
+		if resp.StatusCode != http.StatusTooManyRequests || resp.Header.Get("Retry-After") != "1" {
+			t.Fatalf("expected injected error, got status code %d", resp.StatusCode)
+		}
+	}
+	if resp := post(t, server, "translate", "key", values); resp.StatusCode != http.StatusOK {
+		t.Fatalf("injected error has been returned too often: %d", resp.StatusCode)
+	}
+	requests := server.Requests()
+	if len(requests) != 4 || requests[3].Path != "translate" || requests[3].Form.Get("text") != "Hallo" ||
+		requests[3].Header.Get("Authorization") != "DeepL-Auth-Key key" {
+		t.Fatalf("unexpected recorded requests: %+v", requests)
+	}
+}
+
+// TestServerAuthKey tests whether requests with a wrong auth key are rejected.
+func TestServerAuthKey(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	server.SetAuthKey("secret")
+	if resp := post(t, server, "usage", "wrong", nil); resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected status code 403, got %d", resp.StatusCode)
+	}
+	if resp := post(t, server, "usage", "secret", nil); resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status code 200, got %d", resp.StatusCode)
+	}
+}
+
+// TestServerConcurrency tests whether requests are served concurrently and the translate function may access the
+// server.
+func TestServerConcurrency(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	// both translations have to run at the same time in order to pass the barrier
+	var barrier sync.WaitGroup
+	barrier.Add(2)
+	server.SetTranslateFunc(func(text, sourceLang, targetLang string) string {
+		barrier.Done()
+		done := make(chan struct{})
+		go func() {
+			barrier.Wait()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			return "timeout"
+		}
+		return text + " " + strconv.FormatInt(server.CharacterCount(), 10)
+	})
+	var wait sync.WaitGroup
+	results := make([]string, 2)
+	for i := range results {
+		wait.Add(1)
+		go func(i int) {
+			defer wait.Done()
+			values := url.Values{"text": {"Hallo"}, "target_lang": {"EN-GB"}}
+			req, _ := http.NewRequest(http.MethodPost, server.EndpointUrl()+"translate",
+				strings.NewReader(values.Encode()))
+			req.Header.Set("Authorization", "DeepL-Auth-Key key")
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			resp, err := server.Client().Do(req)
+			if err != nil {
+				results[i] = err.Error()
+				return
+			}
+			defer func() {
+				_ = resp.Body.Close()
+			}()
+			result := &struct {
+				Translations []struct {
+					Text string `json:"text"`
+				} `json:"translations"`
+			}{}
+			if err = json.NewDecoder(resp.Body).Decode(result); err != nil || len(result.Translations) != 1 {
+				results[i] = "invalid response"
+				return
+			}
+			results[i] = result.Translations[0].Text
+		}(i)
+	}
+	wait.Wait()
+	for _, result := range results {
+		if result != "Hallo 10" {
+			t.Fatalf("unexpected results: %v", results)
+		}
+	}
+}
@@ -2,19 +2,23 @@ package deeplclient
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
-	"fmt"
 	"io"
 	"mime/multipart"
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
 )
 
 const (
 	documentTranslateFunctionUri          = "document"
 	documentTranslateResultFunctionSubUri = "result"
+	// default bounds of the interval between two status checks of a blocking document translation
+	defaultMinPollInterval = time.Second
+	defaultMaxPollInterval = 30 * time.Second
 )
 
 // DocumentTranslationStartRequest contains the payload data for each document translation request
@@ -67,19 +71,19 @@ const (
 // function
 type DocumentTranslationStatusResponse struct {
 	// DocumentID is the unique ID of the document
-	DocumentId string
+	DocumentId string `json:"document_id"`
 
 	// Status defines the current state of the translation process
-	Status DocumentTranslationStatus
+	Status DocumentTranslationStatus `json:"status"`
 
 	// SecondsRemaining describes the estimated time until the translation is done
-	SecondsRemaining uint
+	SecondsRemaining uint `json:"seconds_remaining"`
 
 	// BilledCharacters is the amount of characters billed
-	BilledCharacters uint
+	BilledCharacters uint `json:"billed_characters"`
 
 	// ErrorMessage describes an error during translation, if one occurred (if not the value is nil)
-	ErrorMessage *string
+	ErrorMessage *string `json:"error_message"`
 }
 
 // DocumentTranslationDownloadRequest and DocumentTranslationStartResponse share the same fields
@@ -89,67 +93,100 @@ type DocumentTranslationDownloadRequest DocumentTranslationStartResponse
 // error if something went wrong
 func (client *Client) StartDocumentTranslate(req *DocumentTranslationStartRequest) (
 	resp *DocumentTranslationStartResponse, err error) {
-	body := &bytes.Buffer{}
-	writer := multipart.NewWriter(body)
+	return client.StartDocumentTranslateWithContext(context.Background(), req)
+}
 
+// StartDocumentTranslateWithContext starts the translation process of a given document and returns document
+// information or an error if something went wrong. The upload is aborted as soon as the given context is done.
+func (client *Client) StartDocumentTranslateWithContext(ctx context.Context, req *DocumentTranslationStartRequest) (
+	resp *DocumentTranslationStartResponse, err error) {
+	ctx, span := client.startDocumentSpan(ctx, req)
+	defer func() {
+		endDocumentSpan(span, resp, err)
+	}()
 	if len(req.File) == 0 {
 		return resp, errors.New("'File' field most not be empty")
 	}
+	if err = client.validateDocumentTranslationStartRequest(req); err != nil {
+		return resp, err
+	}
+	if err = client.tenants.reserve(ctx, 0); err != nil {
+		return nil, err
+	}
+	if err = client.quota.reserve(ctx, 0); err != nil {
+		return nil, err
+	}
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	if err = req.writeMultipart(writer, bytes.NewReader(req.File)); err != nil {
+		return nil, err
+	}
+
+	var httpResp *http.Response
+	// the document is uploaded with the key which created its glossary, if any
+	ctx = client.keys.bindCreation(client.keys.resourceContext(ctx, glossaryFunctionUri, string(req.GlossaryId)))
+	httpResp, err = client.doApiFunctionWithMultipartForm(ctx, documentTranslateFunctionUri, http.MethodPost,
+		writer.Boundary(), body)
+	if err != nil {
+		return
+	}
+	return client.decodeDocumentTranslationStartResponse(ctx, httpResp)
+}
+
+// validateDocumentTranslationStartRequest checks all fields of the request except the file.
+func (client *Client) validateDocumentTranslationStartRequest(req *DocumentTranslationStartRequest) error {
 	if len(req.Filename) == 0 {
-		return resp, errors.New("'Filename' field must not be empty")
+		return errors.New("'Filename' field must not be empty")
 	}
+	return client.validateLangs(req.SourceLang, req.TargetLang)
+}
+
+// writeMultipart writes the given file and all other fields of the request into the multipart writer and closes it.
+// The field File of the request is ignored.
+func (req *DocumentTranslationStartRequest) writeMultipart(writer *multipart.Writer, file io.Reader) (err error) {
 	filePart, err := writer.CreateFormFile("file", req.Filename)
 	if err != nil {
-		return resp, err
+		return err
 	}
-
-	if _, err = filePart.Write(req.File); err != nil {
-		return resp, err
+	if _, err = io.Copy(filePart, file); err != nil {
+		return err
 	}
 
 	if req.SourceLang != "" {
 		if err = writer.WriteField("source_lang", req.SourceLang.String()); err != nil {
-			return resp, err
+			return err
 		}
 	}
-	if len(req.TargetLang) == 0 {
-		return resp, errors.New("'TargetLang' field of translation request cannot be omitted")
-	}
-	if req.SourceLang != "" {
-		if err = writer.WriteField("target_lang", req.TargetLang.String()); err != nil {
-			return resp, err
-		}
+	if err = writer.WriteField("target_lang", req.TargetLang.String()); err != nil {
+		return err
 	}
 	if len(req.Formality) != 0 {
 		if err = writer.WriteField("formality", string(req.Formality)); err != nil {
-			return resp, err
+			return err
 		}
 	}
 	if len(req.GlossaryId) > 0 {
 		if err = writer.WriteField("glossary_id", string(req.GlossaryId)); err != nil {
-			return resp, err
+			return err
 		}
 	}
 	// without closing the writer, the body cannot be read
-	err = writer.Close()
-	if err != nil {
-		return nil, err
-	}
+	return writer.Close()
+}
 
-	var httpResp *http.Response
-	httpResp, err = client.doApiFunctionWithMultipartForm(documentTranslateFunctionUri, http.MethodPost,
-		writer.Boundary(), body)
-	if err != nil {
+// decodeDocumentTranslationStartResponse parses the response of the document translation API function and records
+// the uploaded document for the tenant and key of the context.
+func (client *Client) decodeDocumentTranslationStartResponse(ctx context.Context, httpResp *http.Response) (
+	resp *DocumentTranslationStartResponse, err error) {
+	defer client.closeBody(httpResp.Body, "document translation")
+	resp = &DocumentTranslationStartResponse{}
+	if err = json.NewDecoder(httpResp.Body).Decode(resp); err != nil {
 		return
 	}
-	defer func(Body io.ReadCloser) {
-		err := Body.Close()
-		if err != nil {
-			fmt.Printf("could not close response of document translation request%e\n", err)
-		}
-	}(httpResp.Body)
-	resp = &DocumentTranslationStartResponse{}
-	err = json.NewDecoder(httpResp.Body).Decode(resp)
+	client.keys.pin(ctx, documentTranslateFunctionUri, resp.DocumentId)
+	if ledgerErr := client.tenants.startDocument(ctx, resp.DocumentId); ledgerErr != nil {
+		client.debug("could not record document of tenant", "error", ledgerErr.Error())
+	}
 	return
 }
 
@@ -157,6 +194,21 @@ func (client *Client) StartDocumentTranslate(req *DocumentTranslationStartReques
 // went wrong
 func (client *Client) CheckDocumentTranslationStatus(req *DocumentTranslationStatusRequest) (
 	resp *DocumentTranslationStatusResponse, err error) {
+	return client.CheckDocumentTranslationStatusWithContext(context.Background(), req)
+}
+
+// CheckDocumentTranslationStatusWithContext returns the current status of the document translation or an error, if
+// something went wrong. The request is cancelled as soon as the given context is done.
+func (client *Client) CheckDocumentTranslationStatusWithContext(ctx context.Context,
+	req *DocumentTranslationStatusRequest) (resp *DocumentTranslationStatusResponse, err error) {
+	ctx, span := client.startSpan(ctx, "deepl.check_document_translation_status")
+	defer func() {
+		if resp != nil {
+			span.SetAttribute(AttributeDocumentStatus, string(resp.Status))
+		}
+		endSpan(span, err)
+	}()
+	span.SetAttribute(AttributeDocumentId, req.DocumentId)
 	values := &url.Values{}
 
 	if len(strings.TrimSpace(req.DocumentId)) == 0 {
@@ -169,59 +221,150 @@ func (client *Client) CheckDocumentTranslationStatus(req *DocumentTranslationSta
 	values.Add("document_key", req.DocumentKey)
 
 	var httpResp *http.Response
-	httpResp, err = client.doApiFunction(documentTranslateFunctionUri+"/"+req.DocumentId, http.MethodPost, values)
+	ctx = client.keys.resourceContext(ctx, documentTranslateFunctionUri, req.DocumentId)
+	httpResp, err = client.doApiFunction(ctx, documentTranslateFunctionUri+"/"+req.DocumentId, http.MethodPost, values)
 	if err != nil {
 		return
 	}
-	defer func(Body io.ReadCloser) {
-		err := Body.Close()
-		if err != nil {
-			fmt.Printf("could not close response of document translation check request%e\n", err)
-		}
-	}(httpResp.Body)
+	defer client.closeBody(httpResp.Body, "document translation check")
 
 	resp = &DocumentTranslationStatusResponse{}
-	err = json.NewDecoder(httpResp.Body).Decode(resp)
-	if err != nil {
+	if err = json.NewDecoder(httpResp.Body).Decode(resp); err != nil {
+		return
+	}
+	client.quota.recordDocument(resp)
+	if ledgerErr := client.tenants.recordDocument(resp); ledgerErr != nil {
+		client.debug("could not record billed characters of tenant", "error", ledgerErr.Error())
+	}
+	if resp.Status == StatusDone {
+		client.debug("deepl document translated", "document", req.DocumentId, "billed_characters",
+			resp.BilledCharacters)
+	}
+	if resp.Status == StatusError {
+		// failed documents cannot be downloaded, so they are never accessed again
+		client.keys.unpin(documentTranslateFunctionUri, req.DocumentId)
+		translationErr := &DocumentTranslationErr{DocumentId: req.DocumentId}
 		if resp.ErrorMessage != nil {
-			err = errors.New(*resp.ErrorMessage)
-		} else {
-			// this may happen if the filename does not match the file content (e.g. txt-filename when file is a
-			// Microsoft Word file)
-			if resp.Status == StatusError {
-				err = errors.New("an unspecified error occurred during translation")
-			}
+			translationErr.Message = *resp.ErrorMessage
 		}
+		err = translationErr
 	}
 	return
 }
 
 // DownloadTranslatedDocument returns the translated document or an error, if something went wrong
 func (client *Client) DownloadTranslatedDocument(req *DocumentTranslationDownloadRequest) (result []byte, err error) {
-	values := &url.Values{}
+	return client.DownloadTranslatedDocumentWithContext(context.Background(), req)
+}
 
-	if len(strings.TrimSpace(req.DocumentId)) == 0 {
-		return nil, errors.New("'DocumentId' must not be empty")
+// DownloadTranslatedDocumentWithContext returns the translated document or an error, if something went wrong. The
+// download is aborted as soon as the given context is done.
+func (client *Client) DownloadTranslatedDocumentWithContext(ctx context.Context,
+	req *DocumentTranslationDownloadRequest) (result []byte, err error) {
+	buffer := &bytes.Buffer{}
+	if _, err = client.DownloadTranslatedDocumentToWithContext(ctx, req, buffer, nil); err != nil {
+		return nil, err
 	}
+	return buffer.Bytes(), nil
+}
 
-	if len(strings.TrimSpace(req.DocumentKey)) == 0 {
-		return nil, errors.New("'DocumentKey' must not be empty")
+// DocumentTranslateOptions configures a blocking document translation (see TranslateDocument).
+type DocumentTranslateOptions struct {
+	// MinPollInterval is the minimum time between two status checks. Defaults to one second.
+	MinPollInterval time.Duration
+	// MaxPollInterval is the maximum time between two status checks. Defaults to 30 seconds.
+	MaxPollInterval time.Duration
+	// Progress is called with the current status after every status check, if set.
+	Progress func(status *DocumentTranslationStatusResponse)
+	// UploadProgress is called with the amount of bytes uploaded so far by streaming document translations, if set.
+	UploadProgress ProgressFunc
+	// DownloadProgress is called with the amount of bytes downloaded so far by streaming document translations, if
+	// set.
+	DownloadProgress ProgressFunc
+}
+
+// pollInterval returns the time to wait before the next status check. The estimation of the server is preferred,
+// otherwise the interval grows exponentially with every check.
+func (options *DocumentTranslateOptions) pollInterval(status *DocumentTranslationStatusResponse,
+	previous time.Duration) time.Duration {
+	minInterval, maxInterval := defaultMinPollInterval, defaultMaxPollInterval
+	if options != nil && options.MinPollInterval > 0 {
+		minInterval = options.MinPollInterval
 	}
-	values.Add("document_key", req.DocumentKey)
+	if options != nil && options.MaxPollInterval > 0 {
+		maxInterval = options.MaxPollInterval
+	}
+	interval := previous * 2
+	if status.SecondsRemaining > 0 {
+		interval = time.Duration(status.SecondsRemaining) * time.Second
+	}
+	if interval < minInterval {
+		interval = minInterval
+	}
+	if interval > maxInterval {
+		interval = maxInterval
+	}
+	return interval
+}
 
-	var httpResp *http.Response
-	httpResp, err = client.doApiFunction(
-		documentTranslateFunctionUri+"/"+req.DocumentId+"/"+documentTranslateResultFunctionSubUri,
-		http.MethodPost, values)
+// TranslateDocument uploads the document, waits until its translation is done and returns the translated document
+// or an error if something went wrong. See TranslateDocumentWithContext for more details.
+func (client *Client) TranslateDocument(req *DocumentTranslationStartRequest, options *DocumentTranslateOptions) (
+	[]byte, error) {
+	return client.TranslateDocumentWithContext(context.Background(), req, options)
+}
+
+// TranslateDocumentWithContext uploads the document, waits until its translation is done and returns the translated
+// document or an error if something went wrong. The options may be nil to use the defaults.
+//
+// The status of the translation is checked as soon as the server estimates the translation to be done. If the
+// translation fails, a *DocumentTranslationErr is returned. The whole process is cancelled as soon as the given context
+// is done.
+func (client *Client) TranslateDocumentWithContext(ctx context.Context, req *DocumentTranslationStartRequest,
+	options *DocumentTranslateOptions) (result []byte, err error) {
+	ctx, span := client.startSpan(ctx, "deepl.translate_document")
+	defer func() {
+		endSpan(span, err)
+	}()
+	span.SetAttribute(AttributeSourceLang, req.SourceLang.String())
+	span.SetAttribute(AttributeTargetLang, req.TargetLang.String())
+	return TranslateDocumentWithTranslator(ctx, client, req, options)
+}
+
+// TranslateDocumentWithTranslator works like Client.TranslateDocumentWithContext, but uses the given translator for
+// all API calls. This allows to use decorated translators (e.g. WithRetries) for the whole lifecycle of a document.
+func TranslateDocumentWithTranslator(ctx context.Context, translator DocumentTranslator,
+	req *DocumentTranslationStartRequest, options *DocumentTranslateOptions) ([]byte, error) {
+	document, err := translator.StartDocumentTranslateWithContext(ctx, req)
 	if err != nil {
-		return
+		return nil, err
+	}
+	if err = waitForDocumentTranslation(ctx, translator, document, options); err != nil {
+		return nil, err
 	}
-	defer func(Body io.ReadCloser) {
-		err := Body.Close()
+	return translator.DownloadTranslatedDocumentWithContext(ctx, (*DocumentTranslationDownloadRequest)(document))
+}
+
+// waitForDocumentTranslation checks the status of the document translation until it is done, failed or the context
+// is done.
+func waitForDocumentTranslation(ctx context.Context, translator DocumentTranslator,
+	document *DocumentTranslationStartResponse, options *DocumentTranslateOptions) error {
+	var interval time.Duration
+	for {
+		status, err := translator.CheckDocumentTranslationStatusWithContext(ctx,
+			(*DocumentTranslationStatusRequest)(document))
+		if status != nil && options != nil && options.Progress != nil {
+			options.Progress(status)
+		}
 		if err != nil {
-			fmt.Printf("could not close response of document translation download request%e\n", err)
+			return err
 		}
-	}(httpResp.Body)
-	result, err = io.ReadAll(httpResp.Body)
-	return
+		if status.Status == StatusDone {
+			return nil
+		}
+		interval = options.pollInterval(status, interval)
+		if err = sleepContext(ctx, interval); err != nil {
+			return err
+		}
+	}
 }
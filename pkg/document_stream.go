@@ -0,0 +1,196 @@
+package deeplclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ProgressFunc is called with the total amount of bytes transferred so far whenever a chunk of a document has been
+// uploaded or downloaded.
+type ProgressFunc func(transferred int64)
+
+// progressReader reports the amount of bytes read from the underlying reader.
+type progressReader struct {
+	reader      io.Reader
+	progress    ProgressFunc
+	transferred int64
+}
+
+// Read reads from the underlying reader and reports the progress.
+func (reader *progressReader) Read(p []byte) (n int, err error) {
+	n, err = reader.reader.Read(p)
+	if n > 0 {
+		reader.transferred += int64(n)
+		reader.progress(reader.transferred)
+	}
+	return
+}
+
+// progressWriter reports the amount of bytes written to the underlying writer.
+type progressWriter struct {
+	writer      io.Writer
+	progress    ProgressFunc
+	transferred int64
+}
+
+// Write writes to the underlying writer and reports the progress.
+func (writer *progressWriter) Write(p []byte) (n int, err error) {
+	n, err = writer.writer.Write(p)
+	if n > 0 {
+		writer.transferred += int64(n)
+		writer.progress(writer.transferred)
+	}
+	return
+}
+
+// StartDocumentTranslateFromReader starts the translation process of the document read from the given reader and
+// returns document information or an error if something went wrong. See StartDocumentTranslateFromReaderWithContext
+// for more details.
+func (client *Client) StartDocumentTranslateFromReader(req *DocumentTranslationStartRequest, file io.Reader,
+	progress ProgressFunc) (*DocumentTranslationStartResponse, error) {
+	return client.StartDocumentTranslateFromReaderWithContext(context.Background(), req, file, progress)
+}
+
+// StartDocumentTranslateFromReaderWithContext starts the translation process of the document read from the given
+// reader and returns document information or an error if something went wrong. The field File of the request is
+// ignored.
+//
+// In contrast to StartDocumentTranslateWithContext, the document is streamed to the API server without buffering it
+// in memory. Therefore, the upload is never retried. If progress is not nil, it is called with the amount of bytes of
+// the document uploaded so far. The upload is aborted as soon as the given context is done.
+func (client *Client) StartDocumentTranslateFromReaderWithContext(ctx context.Context,
+	req *DocumentTranslationStartRequest, file io.Reader, progress ProgressFunc) (
+	resp *DocumentTranslationStartResponse, err error) {
+	ctx, span := client.startDocumentSpan(ctx, req)
+	defer func() {
+		endDocumentSpan(span, resp, err)
+	}()
+	if file == nil {
+		return nil, errors.New("file reader must not be nil")
+	}
+	if err = client.validateDocumentTranslationStartRequest(req); err != nil {
+		return nil, err
+	}
+	if err = client.tenants.reserve(ctx, 0); err != nil {
+		return nil, err
+	}
+	if err = client.quota.reserve(ctx, 0); err != nil {
+		return nil, err
+	}
+	if progress != nil {
+		file = &progressReader{reader: file, progress: progress}
+	}
+
+	pipeReader, pipeWriter := io.Pipe()
+	// stop writing the body if the request has been finished before the body has been read completely
+	defer func() {
+		_ = pipeReader.Close()
+	}()
+	writer := multipart.NewWriter(pipeWriter)
+	go func() {
+		_ = pipeWriter.CloseWithError(req.writeMultipart(writer, file))
+	}()
+
+	var httpResp *http.Response
+	// the document is uploaded with the key which created its glossary, if any
+	ctx = client.keys.bindCreation(client.keys.resourceContext(ctx, glossaryFunctionUri, string(req.GlossaryId)))
+	httpResp, err = client.doApiFunctionWithMultipartStream(ctx, documentTranslateFunctionUri, http.MethodPost,
+		writer.Boundary(), pipeReader)
+	if err != nil {
+		return
+	}
+	return client.decodeDocumentTranslationStartResponse(ctx, httpResp)
+}
+
+// DownloadTranslatedDocumentTo writes the translated document into the given writer and returns the amount of bytes
+// written or an error if something went wrong. See DownloadTranslatedDocumentToWithContext for more details.
+func (client *Client) DownloadTranslatedDocumentTo(req *DocumentTranslationDownloadRequest, w io.Writer,
+	progress ProgressFunc) (int64, error) {
+	return client.DownloadTranslatedDocumentToWithContext(context.Background(), req, w, progress)
+}
+
+// DownloadTranslatedDocumentToWithContext writes the translated document into the given writer and returns the amount
+// of bytes written or an error if something went wrong.
+//
+// In contrast to DownloadTranslatedDocumentWithContext, the document is streamed into the writer without buffering it
+// in memory. If progress is not nil, it is called with the amount of bytes of the document downloaded so far. The
+// download is aborted as soon as the given context is done.
+func (client *Client) DownloadTranslatedDocumentToWithContext(ctx context.Context,
+	req *DocumentTranslationDownloadRequest, w io.Writer, progress ProgressFunc) (written int64, err error) {
+	ctx, span := client.startSpan(ctx, "deepl.download_translated_document")
+	defer func() {
+		span.SetAttribute(AttributeBytes, written)
+		endSpan(span, err)
+	}()
+	span.SetAttribute(AttributeDocumentId, req.DocumentId)
+	values := &url.Values{}
+
+	if len(strings.TrimSpace(req.DocumentId)) == 0 {
+		return 0, errors.New("'DocumentId' must not be empty")
+	}
+
+	if len(strings.TrimSpace(req.DocumentKey)) == 0 {
+		return 0, errors.New("'DocumentKey' must not be empty")
+	}
+	values.Add("document_key", req.DocumentKey)
+
+	var httpResp *http.Response
+	ctx = client.keys.resourceContext(ctx, documentTranslateFunctionUri, req.DocumentId)
+	httpResp, err = client.doApiFunction(ctx,
+		documentTranslateFunctionUri+"/"+req.DocumentId+"/"+documentTranslateResultFunctionSubUri,
+		http.MethodPost, values)
+	if err != nil {
+		return
+	}
+	defer client.closeBody(httpResp.Body, "document translation download")
+	if progress != nil {
+		w = &progressWriter{writer: w, progress: progress}
+	}
+	if written, err = io.Copy(w, httpResp.Body); err == nil {
+		client.keys.unpin(documentTranslateFunctionUri, req.DocumentId)
+	}
+	return
+}
+
+// TranslateDocumentStream uploads the document read from the given reader, waits until its translation is done and
+// writes the translated document into the given writer. See TranslateDocumentStreamWithContext for more details.
+func (client *Client) TranslateDocumentStream(req *DocumentTranslationStartRequest, file io.Reader, w io.Writer,
+	options *DocumentTranslateOptions) error {
+	return client.TranslateDocumentStreamWithContext(context.Background(), req, file, w, options)
+}
+
+// TranslateDocumentStreamWithContext uploads the document read from the given reader, waits until its translation is
+// done and writes the translated document into the given writer. The field File of the request is ignored and the
+// options may be nil to use the defaults.
+//
+// In contrast to TranslateDocumentWithContext, neither the document nor the translated document are buffered in
+// memory. The upload and download progress is reported to the corresponding functions of the options. The whole
+// process is cancelled as soon as the given context is done.
+func (client *Client) TranslateDocumentStreamWithContext(ctx context.Context, req *DocumentTranslationStartRequest,
+	file io.Reader, w io.Writer, options *DocumentTranslateOptions) (err error) {
+	ctx, span := client.startSpan(ctx, "deepl.translate_document")
+	defer func() {
+		endSpan(span, err)
+	}()
+	span.SetAttribute(AttributeSourceLang, req.SourceLang.String())
+	span.SetAttribute(AttributeTargetLang, req.TargetLang.String())
+	var uploadProgress, downloadProgress ProgressFunc
+	if options != nil {
+		uploadProgress, downloadProgress = options.UploadProgress, options.DownloadProgress
+	}
+	document, err := client.StartDocumentTranslateFromReaderWithContext(ctx, req, file, uploadProgress)
+	if err != nil {
+		return err
+	}
+	if err = waitForDocumentTranslation(ctx, client, document, options); err != nil {
+		return err
+	}
+	_, err = client.DownloadTranslatedDocumentToWithContext(ctx, (*DocumentTranslationDownloadRequest)(document), w,
+		downloadProgress)
+	return err
+}
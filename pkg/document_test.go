@@ -0,0 +1,82 @@
+package deeplclient
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fastPolling lets blocking document translations check the status without delay.
+var fastPolling = &DocumentTranslateOptions{
+	MinPollInterval: time.Millisecond,
+	MaxPollInterval: time.Millisecond,
+}
+
+// TestTranslateDocument tests the whole lifecycle of a blocking document translation.
+func TestTranslateDocument(t *testing.T) {
+	client, _ := newFakeClient(t)
+	var statuses []*DocumentTranslationStatusResponse
+	options := *fastPolling
+	options.Progress = func(status *DocumentTranslationStatusResponse) {
+		statuses = append(statuses, status)
+	}
+	result, err := client.TranslateDocument(&DocumentTranslationStartRequest{
+		TargetLang: LangENUS,
+		File:       []byte("Hallo Welt!"),
+		Filename:   "hello.txt",
+	}, &options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(result) != "[EN-US] Hallo Welt!" {
+		t.Fatalf("unexpected translated document: %q", result)
+	}
+	if len(statuses) != 3 || statuses[0].Status != StatusQueued || statuses[1].Status != StatusTranslating ||
+		statuses[2].Status != StatusDone || statuses[2].BilledCharacters != 11 {
+		t.Fatalf("unexpected progress reports: %+v", statuses)
+	}
+}
+
+// TestTranslateDocumentError tests whether a failed document translation is reported as typed error.
+func TestTranslateDocumentError(t *testing.T) {
+	client, server := newFakeClient(t)
+	server.SetDocumentError("Invalid file")
+	_, err := client.TranslateDocument(&DocumentTranslationStartRequest{
+		TargetLang: LangENUS,
+		File:       []byte("Hallo Welt!"),
+		Filename:   "hello.docx",
+	}, fastPolling)
+	var translationErr *DocumentTranslationErr
+	if !errors.As(err, &translationErr) || translationErr.Message != "Invalid file" {
+		t.Fatalf("expected document translation error, got: %v", err)
+	}
+}
+
+// TestTranslateDocumentStream tests whether documents are streamed from a reader into a writer.
+func TestTranslateDocumentStream(t *testing.T) {
+	client, _ := newFakeClient(t)
+	var uploaded, downloaded int64
+	result := &bytes.Buffer{}
+	options := *fastPolling
+	options.UploadProgress = func(transferred int64) {
+		uploaded = transferred
+	}
+	options.DownloadProgress = func(transferred int64) {
+		downloaded = transferred
+	}
+	err := client.TranslateDocumentStream(&DocumentTranslationStartRequest{
+		TargetLang: LangENUS,
+		Filename:   "hello.txt",
+	}, strings.NewReader("Hallo Welt!"), result, &options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.String() != "[EN-US] Hallo Welt!" {
+		t.Fatalf("unexpected translated document: %q", result)
+	}
+	if uploaded != int64(len("Hallo Welt!")) || downloaded != int64(result.Len()) {
+		t.Fatalf("unexpected progress: uploaded %d bytes, downloaded %d bytes", uploaded, downloaded)
+	}
+}
@@ -1,11 +1,18 @@
 package deeplclient
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
+	"net/http"
 	"strconv"
+	"strings"
 )
 
 // UnwrappedApiResponseCodeErr represents an error if the API server returns an unexpected status code.
+//
+// Deprecated: unexpected status codes are returned as APIError, which matches an UnwrappedApiResponseCodeErr with the
+// same status code using errors.Is and can be converted into one using errors.As.
 type UnwrappedApiResponseCodeErr int
 
 // Error returns a compact version of all error information in order to implement the error interface.
@@ -13,73 +20,276 @@ func (err UnwrappedApiResponseCodeErr) Error() string {
 	return fmt.Sprintf("server returned unexpected status code: %d", err)
 }
 
-// KnownRequestErrData contains the standard data which can be parsed from json for every error.
-type KnownRequestErrData struct {
-	// Message holds the error message returned by the server.
+// maxErrorBodySize is the maximum amount of bytes of an error response which is read and kept by APIError.
+const maxErrorBodySize = 64 * 1024
+
+// APIError contains all details of an error response returned by the API server. It is returned for unexpected
+// status codes and embedded by all errors of known status codes, so it can always be obtained with errors.As. Use
+// errors.Is with the sentinel errors (e.g. ErrQuotaExceeded) to check for a specific kind of error.
+type APIError struct {
+	// StatusCode is the HTTP status code of the response.
+	StatusCode int `json:"-"`
+	// Message holds the error message returned by the server. It is empty if the body could not be parsed.
 	Message string `json:"message"`
+	// Detail holds additional information about the error returned by the server, if any.
+	Detail string `json:"detail"`
+	// Method is the HTTP method of the failed request.
+	Method string `json:"-"`
+	// URL is the URL of the failed request.
+	URL string `json:"-"`
+	// Header contains the header of the response.
+	Header http.Header `json:"-"`
+	// Body contains the raw body of the response (truncated to 64 KiB).
+	Body []byte `json:"-"`
 }
 
-// WrongRequestErr indicates the response code 400 returned by the remote API server and contains the error message.
-type WrongRequestErr struct {
-	*KnownRequestErrData
+// KnownRequestErrData is the former name of APIError. The errors of known status codes still embed the APIError under
+// this name, so code accessing the embedded field directly (e.g. err.KnownRequestErrData.Message) keeps working.
+type KnownRequestErrData = APIError
+
+// newAPIError reads the error response and parses its body. A body which is no valid JSON is kept as raw body only.
+func newAPIError(resp *http.Response) *APIError {
+	apiErr := &APIError{
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+	}
+	if resp.Request != nil {
+		apiErr.Method = resp.Request.Method
+		apiErr.URL = redactUrl(resp.Request.URL.String())
+	}
+	if body, err := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize)); err == nil {
+		apiErr.Body = body
+		_ = json.Unmarshal(body, apiErr)
+	}
+	return apiErr
 }
 
 // Error returns a compact version of all error information in order to implement the error interface.
-func (err *WrongRequestErr) Error() string {
-	return fmt.Sprintf("server returned status code 400 (wrong request): %s", strconv.Quote(err.Message))
+func (err *APIError) Error() string {
+	if err == nil {
+		return "server returned an error"
+	}
+	msg := fmt.Sprintf("server returned status code %d (%s)", err.StatusCode, statusText(err.StatusCode))
+	if err.Message != "" {
+		msg += ": " + strconv.Quote(err.Message)
+	}
+	if err.Detail != "" {
+		msg += " (" + err.Detail + ")"
+	}
+	return msg
 }
 
-// AuthFailedErr indicates the response code 403 returned by the remote API server and contains the error message.
-// Normally this error occurs if an invalid auth token was provided.
-type AuthFailedErr struct {
-	*KnownRequestErrData
+// Is reports whether the error matches the target, which is either one of the sentinel errors or an
+// UnwrappedApiResponseCodeErr with the same status code.
+func (err *APIError) Is(target error) bool {
+	if err == nil {
+		return false
+	}
+	switch target := target.(type) {
+	case *statusErr:
+		return target.match(err.StatusCode)
+	case UnwrappedApiResponseCodeErr:
+		return int(target) == err.StatusCode
+	default:
+		return false
+	}
 }
 
-// Error returns a compact version of all error information in order to implement the error interface.
-func (err *AuthFailedErr) Error() string {
-	return fmt.Sprintf("server returned status code 403 (autorization failed)")
+// legacyStatusCodes contains the status codes which have been reported by dedicated error types before APIError has
+// been introduced. All other status codes have been reported as UnwrappedApiResponseCodeErr.
+var legacyStatusCodes = map[int]bool{
+	http.StatusBadRequest:            true,
+	http.StatusForbidden:             true,
+	http.StatusNotFound:              true,
+	http.StatusRequestEntityTooLarge: true,
+	http.StatusTooManyRequests:       true,
+	StatusQuotaExceeded:              true,
 }
 
-// RequestEntityTooLargeErr indicates the response code 413 returned by the remote API server and contains the error message.
-// Normally this error occurs if the request size exceeds the current limit.
-type RequestEntityTooLargeErr struct {
-	*KnownRequestErrData
+// As sets the target to the error if it is an **APIError, which converts the dedicated error types embedding the
+// APIError. If the target is an *UnwrappedApiResponseCodeErr, it is set to the status code if the status code has been
+// reported as UnwrappedApiResponseCodeErr by former versions, so errors.As calls written for them keep working.
+func (err *APIError) As(target interface{}) bool {
+	if err == nil {
+		return false
+	}
+	switch target := target.(type) {
+	case **APIError:
+		*target = err
+		return true
+	case *UnwrappedApiResponseCodeErr:
+		if legacyStatusCodes[err.StatusCode] {
+			return false
+		}
+		*target = UnwrappedApiResponseCodeErr(err.StatusCode)
+		return true
+	default:
+		return false
+	}
 }
 
-// Error returns a compact version of all error information in order to implement the error interface.
-func (err *RequestEntityTooLargeErr) Error() string {
-	return fmt.Sprintf("server returned status code 413 (request entity too large): %s", strconv.Quote(err.Message))
+// Retryable returns whether the error is temporary, so the same request may succeed if it is sent again later.
+func (err *APIError) Retryable() bool {
+	if err == nil {
+		return false
+	}
+	return isRetryableStatus(err.StatusCode)
 }
 
-// TooManyRequestsErr indicates the response code 429 returned by the remote API server and contains the error message.
-// Normally this error occurs if too many requests have been sent in a short amount of time.
-type TooManyRequestsErr struct {
-	*KnownRequestErrData
+// statusText returns a short description of the HTTP status code including the unofficial codes used by DeepL.
+func statusText(statusCode int) string {
+	if status, ok := statusErrors[statusCode]; ok {
+		return status.description
+	}
+	if text := http.StatusText(statusCode); text != "" {
+		return strings.ToLower(text)
+	}
+	return "unknown status"
 }
 
-// Error returns a compact version of all error information in order to implement the error interface.
-func (err *TooManyRequestsErr) Error() string {
-	return fmt.Sprintf("server returned status code 429 (too many request): %s", strconv.Quote(err.Message))
+// statusErr is a sentinel error which matches API errors by their status code.
+type statusErr struct {
+	msg   string
+	match func(statusCode int) bool
 }
 
-// QuotaExceededErr indicates the response code 403 returned by the remote API server and contains the error message.
-// Normally this error occurs if the character limit has been reached.
-type QuotaExceededErr struct {
-	*KnownRequestErrData
+// Error returns the description of the sentinel error in order to implement the error interface.
+func (err *statusErr) Error() string {
+	return err.msg
 }
 
-// Error returns a compact version of all error information in order to implement the error interface.
-func (err *QuotaExceededErr) Error() string {
-	return fmt.Sprintf("server returned status code 456 (quota exceeded): %s", strconv.Quote(err.Message))
+// statusIs returns a function matching exactly the given status codes.
+func statusIs(statusCodes ...int) func(int) bool {
+	return func(statusCode int) bool {
+		for _, code := range statusCodes {
+			if code == statusCode {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Sentinel errors which can be used with errors.Is to check for a specific kind of API error.
+var (
+	// ErrWrongRequest matches errors of requests which have been rejected as invalid (status 400).
+	ErrWrongRequest error = &statusErr{"wrong request", statusIs(http.StatusBadRequest)}
+	// ErrAuthFailed matches errors of requests with an invalid auth key (status 403).
+	ErrAuthFailed error = &statusErr{"authorization failed", statusIs(http.StatusForbidden)}
+	// ErrNotFound matches errors of requests for unknown resources (status 404).
+	ErrNotFound error = &statusErr{"not found", statusIs(http.StatusNotFound)}
+	// ErrRequestEntityTooLarge matches errors of requests exceeding the size limit (status 413).
+	ErrRequestEntityTooLarge error = &statusErr{"request entity too large",
+		statusIs(http.StatusRequestEntityTooLarge)}
+	// ErrTooManyRequests matches errors of requests which have been rejected because too many requests have been sent
+	// (status 429 and 529).
+	ErrTooManyRequests error = &statusErr{"too many requests",
+		statusIs(http.StatusTooManyRequests, StatusTooManyRequestsHighLoad)}
+	// ErrQuotaExceeded matches errors of requests which have been rejected because the quota is exceeded (status 456).
+	ErrQuotaExceeded error = &statusErr{"quota exceeded", statusIs(StatusQuotaExceeded)}
+	// ErrRequestURITooLong matches errors of GET requests whose URL exceeds the size limit (status 414).
+	ErrRequestURITooLong error = &statusErr{"request uri too long", statusIs(http.StatusRequestURITooLong)}
+	// ErrUnsupportedMediaType matches errors of requests with an unsupported content type (status 415).
+	ErrUnsupportedMediaType error = &statusErr{"unsupported media type", statusIs(http.StatusUnsupportedMediaType)}
+	// ErrServiceUnavailable matches errors of requests which failed because the service is temporarily unavailable
+	// (status 503 and 504).
+	ErrServiceUnavailable error = &statusErr{"service unavailable",
+		statusIs(http.StatusServiceUnavailable, http.StatusGatewayTimeout)}
+	// ErrServerError matches errors of requests which failed because of an internal error of the server (status 5xx).
+	ErrServerError error = &statusErr{"server error", func(statusCode int) bool {
+		return statusCode >= http.StatusInternalServerError
+	}}
+)
+
+// statusError describes a status code documented by DeepL.
+type statusError struct {
+	// description is the short description of the status code used by the error messages
+	description string
+	// wrap returns the dedicated error type of the status code, if any
+	wrap func(apiErr *APIError) error
 }
 
-// NotFoundErr indicates the response code 404 returned by the remote API server and contains the error message.
-// Normally this error occurs if an undefined API endpoint of an undefined document status/result is requested.
-type NotFoundErr struct {
-	*KnownRequestErrData
+// statusErrors maps all status codes documented by DeepL to their descriptions and dedicated error types. A status
+// code without a dedicated error type is returned as APIError.
+var statusErrors = map[int]statusError{
+	http.StatusBadRequest: {"wrong request", func(apiErr *APIError) error { return &WrongRequestErr{apiErr} }},
+	http.StatusForbidden:  {"authorization failed", func(apiErr *APIError) error { return &AuthFailedErr{apiErr} }},
+	http.StatusNotFound:   {"not found", func(apiErr *APIError) error { return &NotFoundErr{apiErr} }},
+	http.StatusRequestEntityTooLarge: {"request entity too large",
+		func(apiErr *APIError) error { return &RequestEntityTooLargeErr{apiErr} }},
+	http.StatusRequestURITooLong: {"request uri too long",
+		func(apiErr *APIError) error { return &RequestURITooLongErr{apiErr} }},
+	http.StatusUnsupportedMediaType: {"unsupported media type",
+		func(apiErr *APIError) error { return &UnsupportedMediaTypeErr{apiErr} }},
+	http.StatusTooManyRequests: {"too many requests",
+		func(apiErr *APIError) error { return &TooManyRequestsErr{apiErr} }},
+	StatusQuotaExceeded: {"quota exceeded", func(apiErr *APIError) error { return &QuotaExceededErr{apiErr} }},
+	http.StatusInternalServerError: {"internal server error",
+		func(apiErr *APIError) error { return &InternalServerErr{apiErr} }},
+	http.StatusServiceUnavailable: {"service unavailable",
+		func(apiErr *APIError) error { return &ServiceUnavailableErr{apiErr} }},
+	http.StatusGatewayTimeout: {"gateway timeout", func(apiErr *APIError) error { return &GatewayTimeoutErr{apiErr} }},
+	StatusTooManyRequestsHighLoad: {"too many requests (high load)",
+		func(apiErr *APIError) error { return &TooManyRequestsHighLoadErr{apiErr} }},
+}
+
+// The dedicated error types of the status codes documented by DeepL. They embed the APIError, whose methods provide
+// the error message and the support for errors.Is and errors.As (including conversions into the embedded APIError).
+type (
+	// WrongRequestErr indicates the response code 400 returned by the remote API server.
+	WrongRequestErr struct{ *KnownRequestErrData }
+	// AuthFailedErr indicates the response code 403 returned by the remote API server. Normally this error occurs if
+	// an invalid auth token was provided.
+	AuthFailedErr struct{ *KnownRequestErrData }
+	// NotFoundErr indicates the response code 404 returned by the remote API server. Normally this error occurs if an
+	// undefined API endpoint of an undefined document status/result is requested.
+	NotFoundErr struct{ *KnownRequestErrData }
+	// RequestEntityTooLargeErr indicates the response code 413 returned by the remote API server. Normally this error
+	// occurs if the request size exceeds the current limit.
+	RequestEntityTooLargeErr struct{ *KnownRequestErrData }
+	// RequestURITooLongErr indicates the response code 414 returned by the remote API server. Normally this error
+	// occurs if the URL of a GET request (e.g. containing many texts) exceeds the size limit.
+	RequestURITooLongErr struct{ *KnownRequestErrData }
+	// UnsupportedMediaTypeErr indicates the response code 415 returned by the remote API server. Normally this error
+	// occurs if the content type of a request or the requested content type of a response is not supported.
+	UnsupportedMediaTypeErr struct{ *KnownRequestErrData }
+	// TooManyRequestsErr indicates the response code 429 returned by the remote API server. Normally this error occurs
+	// if too many requests have been sent in a short amount of time.
+	TooManyRequestsErr struct{ *KnownRequestErrData }
+	// QuotaExceededErr indicates the response code 456 returned by the remote API server. Normally this error occurs if
+	// the character limit has been reached.
+	QuotaExceededErr struct{ *KnownRequestErrData }
+	// InternalServerErr indicates the response code 500 returned by the remote API server. Normally this error is
+	// temporary, so the request may succeed if it is sent again later.
+	InternalServerErr struct{ *KnownRequestErrData }
+	// ServiceUnavailableErr indicates the response code 503 returned by the remote API server. Normally this error
+	// occurs if the service is down for maintenance or overloaded, or if a translated document is not ready for
+	// download yet.
+	ServiceUnavailableErr struct{ *KnownRequestErrData }
+	// GatewayTimeoutErr indicates the response code 504 returned by the remote API server. Normally this error occurs
+	// if the API server did not respond in time.
+	GatewayTimeoutErr struct{ *KnownRequestErrData }
+	// TooManyRequestsHighLoadErr indicates the response code 529 returned by the remote API server. Normally this
+	// error occurs if the API server is under high load and requests have to be sent less frequently.
+	TooManyRequestsHighLoadErr struct{ *KnownRequestErrData }
+)
+
+// DocumentTranslationErr indicates that the API server failed to translate a document and contains the error message.
+// Normally this error occurs if the document is damaged or the filename does not match the file content (e.g.
+// txt-filename when file is a Microsoft Word file).
+type DocumentTranslationErr struct {
+	// DocumentId is the unique ID of the document which could not be translated.
+	DocumentId string
+	// Message holds the error message returned by the server. It may be empty if the server did not specify the
+	// error.
+	Message string
 }
 
 // Error returns a compact version of all error information in order to implement the error interface.
-func (err *NotFoundErr) Error() string {
-	return fmt.Sprintf("server returned status code 404 (not found): %s", strconv.Quote(err.Message))
+func (err *DocumentTranslationErr) Error() string {
+	if err.Message == "" {
+		return fmt.Sprintf("translation of document %s failed: an unspecified error occurred during translation",
+			err.DocumentId)
+	}
+	return fmt.Sprintf("translation of document %s failed: %s", err.DocumentId, strconv.Quote(err.Message))
 }
@@ -0,0 +1,212 @@
+package deeplclient
+
+import (
+	"errors"
+	"github.com/PineiroHosting/deeplgobindings/pkg/deepltest"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestAPIErrorDetails tests whether API errors match the sentinel errors and carry the details of the response.
+func TestAPIErrorDetails(t *testing.T) {
+	client, server := newFakeClient(t)
+	server.InjectError(deepltest.ErrorInjection{
+		Path:       "usage",
+		StatusCode: StatusQuotaExceeded,
+		Count:      1,
+		Message:    "Quota exceeded",
+	})
+	_, err := client.GetUsage()
+	if !errors.Is(err, ErrQuotaExceeded) || errors.Is(err, ErrTooManyRequests) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var quotaExceededErr *QuotaExceededErr
+	if !errors.As(err, &quotaExceededErr) {
+		t.Fatalf("expected quota exceeded error, got: %T", err)
+	}
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("expected API error, got: %T", err)
+	}
+	if apiErr.StatusCode != StatusQuotaExceeded || apiErr.Message != "Quota exceeded" ||
+		apiErr.Method != http.MethodGet || apiErr.URL == "" || len(apiErr.Body) == 0 {
+		t.Fatalf("unexpected API error details: %+v", apiErr)
+	}
+	if apiErr.Retryable() {
+		t.Fatal("exceeded quota must not be retryable")
+	}
+}
+
+// TestAPIErrorInvalidBody tests whether an error response without valid JSON body still results in the API error.
+func TestAPIErrorInvalidBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte("<html>Bad Request</html>"))
+	}))
+	defer server.Close()
+	_, err := newRetryTestClient(server).GetUsage()
+	var wrongRequestErr *WrongRequestErr
+	if !errors.As(err, &wrongRequestErr) || !errors.Is(err, ErrWrongRequest) {
+		t.Fatalf("expected wrong request error, got: %v", err)
+	}
+	if string(wrongRequestErr.Body) != "<html>Bad Request</html>" {
+		t.Fatalf("unexpected raw body: %q", wrongRequestErr.Body)
+	}
+	// must not panic although the message could not be parsed
+	t.Log(err.Error())
+}
+
+// TestAPIErrorUnexpectedStatus tests whether unexpected status codes are classified correctly.
+func TestAPIErrorUnexpectedStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+	_, err := newRetryTestClient(server).GetUsage()
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || !apiErr.Retryable() {
+		t.Fatalf("expected retryable API error, got: %v", err)
+	}
+	if !errors.Is(err, ErrServerError) || !errors.Is(err, UnwrappedApiResponseCodeErr(http.StatusBadGateway)) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// TestResponseClassification tests whether all documented status codes are classified into their dedicated errors.
+func TestResponseClassification(t *testing.T) {
+	tests := []struct {
+		statusCode int
+		target     error
+		retryable  bool
+		check      func(err error) bool
+	}{
+		{http.StatusBadRequest, ErrWrongRequest, false, func(err error) bool {
+			var typed *WrongRequestErr
+			return errors.As(err, &typed)
+		}},
+		{http.StatusForbidden, ErrAuthFailed, false, func(err error) bool {
+			var typed *AuthFailedErr
+			return errors.As(err, &typed)
+		}},
+		{http.StatusNotFound, ErrNotFound, false, func(err error) bool {
+			var typed *NotFoundErr
+			return errors.As(err, &typed)
+		}},
+		{http.StatusRequestEntityTooLarge, ErrRequestEntityTooLarge, false, func(err error) bool {
+			var typed *RequestEntityTooLargeErr
+			return errors.As(err, &typed)
+		}},
+		{http.StatusRequestURITooLong, ErrRequestURITooLong, false, func(err error) bool {
+			var typed *RequestURITooLongErr
+			return errors.As(err, &typed)
+		}},
+		{http.StatusUnsupportedMediaType, ErrUnsupportedMediaType, false, func(err error) bool {
+			var typed *UnsupportedMediaTypeErr
+			return errors.As(err, &typed)
+		}},
+		{http.StatusTooManyRequests, ErrTooManyRequests, true, func(err error) bool {
+			var typed *TooManyRequestsErr
+			return errors.As(err, &typed)
+		}},
+		{StatusQuotaExceeded, ErrQuotaExceeded, false, func(err error) bool {
+			var typed *QuotaExceededErr
+			return errors.As(err, &typed)
+		}},
+		{http.StatusInternalServerError, ErrServerError, true, func(err error) bool {
+			var typed *InternalServerErr
+			return errors.As(err, &typed)
+		}},
+		{http.StatusServiceUnavailable, ErrServiceUnavailable, true, func(err error) bool {
+			var typed *ServiceUnavailableErr
+			return errors.As(err, &typed)
+		}},
+		{http.StatusGatewayTimeout, ErrServiceUnavailable, true, func(err error) bool {
+			var typed *GatewayTimeoutErr
+			return errors.As(err, &typed)
+		}},
+		{StatusTooManyRequestsHighLoad, ErrTooManyRequests, true, func(err error) bool {
+			var typed *TooManyRequestsHighLoadErr
+			return errors.As(err, &typed)
+		}},
+		{http.StatusTeapot, UnwrappedApiResponseCodeErr(http.StatusTeapot), false, func(err error) bool {
+			_, ok := err.(*APIError)
+			return ok
+		}},
+	}
+	for _, test := range tests {
+		client, server := newFakeClient(t)
+		server.InjectError(deepltest.ErrorInjection{
+			Path:       "translate",
+			StatusCode: test.statusCode,
+			Count:      1,
+			Message:    "injected",
+		})
+		resp, err := client.Translate(&TranslationRequest{Text: "Hallo", TargetLang: LangENGB})
+		if resp != nil {
+			t.Errorf("status %d: unexpected response: %+v", test.statusCode, resp)
+		}
+		if !test.check(err) || !errors.Is(err, test.target) {
+			t.Errorf("status %d: unexpected error: %T %v", test.statusCode, err, err)
+			continue
+		}
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) || apiErr.StatusCode != test.statusCode || apiErr.Message != "injected" {
+			t.Errorf("status %d: unexpected API error details: %+v", test.statusCode, apiErr)
+		} else if apiErr.Retryable() != test.retryable {
+			t.Errorf("status %d: expected retryable to be %v", test.statusCode, test.retryable)
+		}
+	}
+}
+
+// TestInvalidAuthKey tests whether an invalid auth key results in an error instead of an empty response.
+func TestInvalidAuthKey(t *testing.T) {
+	client, server := newFakeClient(t)
+	server.SetAuthKey("valid")
+	resp, err := client.Translate(&TranslationRequest{Text: "Hallo", TargetLang: LangENGB})
+	if resp != nil || !errors.Is(err, ErrAuthFailed) {
+		t.Fatalf("expected auth failed error, got: %+v, %v", resp, err)
+	}
+	var authFailedErr *AuthFailedErr
+	if !errors.As(err, &authFailedErr) || authFailedErr.Message != "Authorization failed" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// TestLegacyErrorCompatibility tests whether code written for the former error types keeps working.
+func TestLegacyErrorCompatibility(t *testing.T) {
+	var code UnwrappedApiResponseCodeErr
+	err := error(&APIError{StatusCode: http.StatusBadGateway})
+	if !errors.As(err, &code) || code != http.StatusBadGateway {
+		t.Fatalf("expected unwrapped status code, got: %d", code)
+	}
+	// status codes with dedicated error types have never been reported as UnwrappedApiResponseCodeErr
+	quotaErr := &QuotaExceededErr{&APIError{StatusCode: StatusQuotaExceeded, Message: "Quota Exceeded"}}
+	if errors.As(quotaErr, &code) {
+		t.Fatal("expected no unwrapped status code for quota exceeded error")
+	}
+	if quotaErr.KnownRequestErrData.Message != "Quota Exceeded" {
+		t.Fatalf("unexpected message: %s", quotaErr.KnownRequestErrData.Message)
+	}
+	if msg := quotaErr.Error(); msg != `server returned status code 456 (quota exceeded): "Quota Exceeded"` {
+		t.Fatalf("unexpected error message: %s", msg)
+	}
+	var apiErr *APIError
+	if !errors.As(quotaErr, &apiErr) || apiErr != quotaErr.KnownRequestErrData {
+		t.Fatal("expected embedded API error")
+	}
+}
+
+// TestZeroValueErrors tests whether errors without embedded API error can be used without panicking.
+func TestZeroValueErrors(t *testing.T) {
+	err := &QuotaExceededErr{}
+	if msg := err.Error(); msg != "server returned an error" {
+		t.Fatalf("unexpected error message: %s", msg)
+	}
+	if err.Retryable() {
+		t.Fatal("expected error to be permanent")
+	}
+	if errors.Is(err, ErrQuotaExceeded) {
+		t.Fatal("expected error without status code not to match")
+	}
+}
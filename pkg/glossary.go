@@ -0,0 +1,322 @@
+package deeplclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	glossaryFunctionUri              = "glossaries"
+	glossaryEntriesFunctionSubUri    = "entries"
+	glossaryLanguagePairsFunctionUri = "glossary-language-pairs"
+	// content type of glossary entries returned by the API server
+	glossaryEntriesContentTypeTSV = "text/tab-separated-values"
+)
+
+// GlossaryEntriesFormat is a wrapper type for the formats glossary entries can be encoded in.
+type GlossaryEntriesFormat string
+
+const (
+	// GlossaryEntriesFormatTSV encodes each entry as a line of source and target term separated by a tab.
+	GlossaryEntriesFormatTSV = GlossaryEntriesFormat("tsv")
+	// GlossaryEntriesFormatCSV encodes each entry as a line of comma-separated values as defined by RFC 4180.
+	GlossaryEntriesFormatCSV = GlossaryEntriesFormat("csv")
+)
+
+// GlossaryEntries maps the source terms of a glossary to their target terms.
+type GlossaryEntries map[string]string
+
+// ParseGlossaryEntries parses glossary entries encoded in the given format.
+func ParseGlossaryEntries(data string, format GlossaryEntriesFormat) (entries GlossaryEntries, err error) {
+	entries = GlossaryEntries{}
+	switch format {
+	case GlossaryEntriesFormatTSV:
+		for lineNumber, line := range strings.Split(data, "\n") {
+			line = strings.TrimSuffix(line, "\r")
+			if len(strings.TrimSpace(line)) == 0 {
+				continue
+			}
+			terms := strings.Split(line, "\t")
+			if len(terms) != 2 {
+				return nil, fmt.Errorf("line %d of glossary entries does not contain exactly two terms", lineNumber+1)
+			}
+			entries[terms[0]] = terms[1]
+		}
+	case GlossaryEntriesFormatCSV:
+		reader := csv.NewReader(strings.NewReader(data))
+		reader.FieldsPerRecord = 2
+		var records [][]string
+		if records, err = reader.ReadAll(); err != nil {
+			return nil, err
+		}
+		for _, record := range records {
+			entries[record[0]] = record[1]
+		}
+	default:
+		return nil, fmt.Errorf("unknown glossary entries format: %s", format)
+	}
+	return
+}
+
+// sortedSourceTerms returns the source terms of all entries in lexical order, so the encoded entries are stable.
+func (entries GlossaryEntries) sortedSourceTerms() []string {
+	sourceTerms := make([]string, 0, len(entries))
+	for source := range entries {
+		sourceTerms = append(sourceTerms, source)
+	}
+	sort.Strings(sourceTerms)
+	return sourceTerms
+}
+
+// TSV encodes the entries as tab-separated values. It returns an error if any term contains a tab or a line break.
+func (entries GlossaryEntries) TSV() (string, error) {
+	var builder strings.Builder
+	for _, source := range entries.sortedSourceTerms() {
+		target := entries[source]
+		if strings.ContainsAny(source, "\t\r\n") || strings.ContainsAny(target, "\t\r\n") {
+			return "", fmt.Errorf("glossary entry %q must not contain tabs or line breaks", source)
+		}
+		builder.WriteString(source)
+		builder.WriteByte('\t')
+		builder.WriteString(target)
+		builder.WriteByte('\n')
+	}
+	return builder.String(), nil
+}
+
+// CSV encodes the entries as comma-separated values.
+func (entries GlossaryEntries) CSV() (string, error) {
+	buffer := &bytes.Buffer{}
+	writer := csv.NewWriter(buffer)
+	for _, source := range entries.sortedSourceTerms() {
+		if err := writer.Write([]string{source, entries[source]}); err != nil {
+			return "", err
+		}
+	}
+	writer.Flush()
+	return buffer.String(), writer.Error()
+}
+
+// Glossary represents the information about a glossary returned by the glossary API functions.
+type Glossary struct {
+	// GlossaryId is the unique ID assigned to the glossary.
+	GlossaryId string `json:"glossary_id"`
+	// Name is the name associated with the glossary.
+	Name string `json:"name"`
+	// Ready indicates whether the glossary can already be used in translation requests.
+	Ready bool `json:"ready"`
+	// SourceLang is the language in which the source texts in the glossary are specified.
+	SourceLang ApiLang `json:"source_lang"`
+	// TargetLang is the language in which the target texts in the glossary are specified.
+	TargetLang ApiLang `json:"target_lang"`
+	// CreationTime is the time when the glossary has been created.
+	CreationTime time.Time `json:"creation_time"`
+	// EntryCount is the number of entries in the glossary.
+	EntryCount int64 `json:"entry_count"`
+}
+
+// GlossaryLanguagePair represents a combination of languages supported by glossaries.
+type GlossaryLanguagePair struct {
+	// SourceLang is the language in which the source texts of a glossary can be specified.
+	SourceLang ApiLang `json:"source_lang"`
+	// TargetLang is the language in which the target texts of a glossary can be specified.
+	TargetLang ApiLang `json:"target_lang"`
+}
+
+// GlossaryCreateRequest contains the payload data for each glossary creation request.
+type GlossaryCreateRequest struct {
+	// Name is the name to be associated with the glossary.
+	Name string
+	// SourceLang is the language in which the source texts in the glossary are specified.
+	SourceLang ApiLang
+	// TargetLang is the language in which the target texts in the glossary are specified.
+	TargetLang ApiLang
+	// Entries contains the entries of the glossary. If empty, RawEntries is used instead.
+	Entries GlossaryEntries
+	// RawEntries contains the entries of the glossary already encoded in the format defined by EntriesFormat.
+	RawEntries string
+	// EntriesFormat defines the format of RawEntries. Defaults to GlossaryEntriesFormatTSV.
+	EntriesFormat GlossaryEntriesFormat
+}
+
+// CreateGlossary creates a glossary and returns its information or an error if something went wrong.
+func (client *Client) CreateGlossary(req *GlossaryCreateRequest) (*Glossary, error) {
+	return client.CreateGlossaryWithContext(context.Background(), req)
+}
+
+// CreateGlossaryWithContext creates a glossary and returns its information or an error if something went wrong. The
+// request is cancelled as soon as the given context is done.
+func (client *Client) CreateGlossaryWithContext(ctx context.Context, req *GlossaryCreateRequest) (
+	resp *Glossary, err error) {
+	values := &url.Values{}
+	if len(strings.TrimSpace(req.Name)) == 0 {
+		return nil, errors.New("'Name' field of glossary creation request must not be empty")
+	}
+	values.Add("name", req.Name)
+	if len(req.SourceLang) == 0 || len(req.TargetLang) == 0 {
+		return nil, errors.New("'SourceLang' and 'TargetLang' fields of glossary creation request cannot be omitted")
+	}
+	values.Add("source_lang", req.SourceLang.String())
+	values.Add("target_lang", req.TargetLang.String())
+	entries, format := req.RawEntries, req.EntriesFormat
+	if len(req.Entries) > 0 {
+		if entries, err = req.Entries.TSV(); err != nil {
+			return nil, err
+		}
+		format = GlossaryEntriesFormatTSV
+	}
+	if len(strings.TrimSpace(entries)) == 0 {
+		return nil, errors.New("glossary creation request must contain at least one entry")
+	}
+	values.Add("entries", entries)
+	if len(format) == 0 {
+		format = GlossaryEntriesFormatTSV
+	}
+	values.Add("entries_format", string(format))
+
+	var httpResp *http.Response
+	ctx = client.keys.bindCreation(ctx)
+	httpResp, err = client.doApiFunction(ctx, glossaryFunctionUri, http.MethodPost, values)
+	if err != nil {
+		return
+	}
+	defer client.closeBody(httpResp.Body, "glossary creation")
+	resp = &Glossary{}
+	if err = json.NewDecoder(httpResp.Body).Decode(resp); err != nil {
+		return nil, err
+	}
+	client.keys.pin(ctx, glossaryFunctionUri, resp.GlossaryId)
+	return
+}
+
+// ListGlossaries returns the information about all glossaries or an error if something went wrong.
+func (client *Client) ListGlossaries() ([]Glossary, error) {
+	return client.ListGlossariesWithContext(context.Background())
+}
+
+// ListGlossariesWithContext returns the information about all glossaries or an error if something went wrong. The
+// request is cancelled as soon as the given context is done.
+func (client *Client) ListGlossariesWithContext(ctx context.Context) (glossaries []Glossary, err error) {
+	var httpResp *http.Response
+	httpResp, err = client.doApiFunction(ctx, glossaryFunctionUri, http.MethodGet, &url.Values{})
+	if err != nil {
+		return
+	}
+	defer client.closeBody(httpResp.Body, "glossary list")
+	resp := &struct {
+		Glossaries []Glossary `json:"glossaries"`
+	}{}
+	if err = json.NewDecoder(httpResp.Body).Decode(resp); err != nil {
+		return nil, err
+	}
+	return resp.Glossaries, nil
+}
+
+// GetGlossary returns the information about the glossary with the given ID or an error if something went wrong.
+func (client *Client) GetGlossary(glossaryId string) (*Glossary, error) {
+	return client.GetGlossaryWithContext(context.Background(), glossaryId)
+}
+
+// GetGlossaryWithContext returns the information about the glossary with the given ID or an error if something went
+// wrong. The request is cancelled as soon as the given context is done.
+func (client *Client) GetGlossaryWithContext(ctx context.Context, glossaryId string) (resp *Glossary, err error) {
+	if len(strings.TrimSpace(glossaryId)) == 0 {
+		return nil, errors.New("glossary ID must not be empty")
+	}
+	var httpResp *http.Response
+	ctx = client.keys.resourceContext(ctx, glossaryFunctionUri, glossaryId)
+	httpResp, err = client.doApiFunction(ctx, glossaryFunctionUri+"/"+url.PathEscape(glossaryId), http.MethodGet,
+		&url.Values{})
+	if err != nil {
+		return
+	}
+	defer client.closeBody(httpResp.Body, "glossary information")
+	resp = &Glossary{}
+	err = json.NewDecoder(httpResp.Body).Decode(resp)
+	return
+}
+
+// GetGlossaryEntries returns the entries of the glossary with the given ID or an error if something went wrong.
+func (client *Client) GetGlossaryEntries(glossaryId string) (GlossaryEntries, error) {
+	return client.GetGlossaryEntriesWithContext(context.Background(), glossaryId)
+}
+
+// GetGlossaryEntriesWithContext returns the entries of the glossary with the given ID or an error if something went
+// wrong. The request is cancelled as soon as the given context is done.
+func (client *Client) GetGlossaryEntriesWithContext(ctx context.Context, glossaryId string) (
+	entries GlossaryEntries, err error) {
+	if len(strings.TrimSpace(glossaryId)) == 0 {
+		return nil, errors.New("glossary ID must not be empty")
+	}
+	var httpResp *http.Response
+	ctx = client.keys.resourceContext(ctx, glossaryFunctionUri, glossaryId)
+	httpResp, err = client.doApiFunctionWithAccept(ctx,
+		glossaryFunctionUri+"/"+url.PathEscape(glossaryId)+"/"+glossaryEntriesFunctionSubUri, http.MethodGet,
+		&url.Values{}, glossaryEntriesContentTypeTSV)
+	if err != nil {
+		return
+	}
+	defer client.closeBody(httpResp.Body, "glossary entries")
+	var data []byte
+	if data, err = io.ReadAll(httpResp.Body); err != nil {
+		return nil, err
+	}
+	return ParseGlossaryEntries(string(data), GlossaryEntriesFormatTSV)
+}
+
+// DeleteGlossary deletes the glossary with the given ID and returns an error if something went wrong.
+func (client *Client) DeleteGlossary(glossaryId string) error {
+	return client.DeleteGlossaryWithContext(context.Background(), glossaryId)
+}
+
+// DeleteGlossaryWithContext deletes the glossary with the given ID and returns an error if something went wrong. The
+// request is cancelled as soon as the given context is done.
+func (client *Client) DeleteGlossaryWithContext(ctx context.Context, glossaryId string) error {
+	if len(strings.TrimSpace(glossaryId)) == 0 {
+		return errors.New("glossary ID must not be empty")
+	}
+	ctx = client.keys.resourceContext(ctx, glossaryFunctionUri, glossaryId)
+	httpResp, err := client.doApiFunction(ctx, glossaryFunctionUri+"/"+url.PathEscape(glossaryId),
+		http.MethodDelete, &url.Values{})
+	if err != nil {
+		return err
+	}
+	client.closeBody(httpResp.Body, "glossary deletion")
+	client.keys.unpin(glossaryFunctionUri, glossaryId)
+	return nil
+}
+
+// GetGlossaryLanguagePairs returns all combinations of languages supported by glossaries or an error if something
+// went wrong.
+func (client *Client) GetGlossaryLanguagePairs() ([]GlossaryLanguagePair, error) {
+	return client.GetGlossaryLanguagePairsWithContext(context.Background())
+}
+
+// GetGlossaryLanguagePairsWithContext returns all combinations of languages supported by glossaries or an error if
+// something went wrong. The request is cancelled as soon as the given context is done.
+func (client *Client) GetGlossaryLanguagePairsWithContext(ctx context.Context) (
+	pairs []GlossaryLanguagePair, err error) {
+	var httpResp *http.Response
+	httpResp, err = client.doApiFunction(ctx, glossaryLanguagePairsFunctionUri, http.MethodGet, &url.Values{})
+	if err != nil {
+		return
+	}
+	defer client.closeBody(httpResp.Body, "glossary language pairs")
+	resp := &struct {
+		SupportedLanguages []GlossaryLanguagePair `json:"supported_languages"`
+	}{}
+	if err = json.NewDecoder(httpResp.Body).Decode(resp); err != nil {
+		return nil, err
+	}
+	return resp.SupportedLanguages, nil
+}
@@ -0,0 +1,79 @@
+package deeplclient
+
+import (
+	"testing"
+)
+
+// TestGlossaryEntriesFormats tests whether glossary entries survive encoding and parsing in all formats.
+func TestGlossaryEntriesFormats(t *testing.T) {
+	entries := GlossaryEntries{"Hallo": "Hello", "Welt, Erde": "World \"Earth\""}
+	for _, format := range []GlossaryEntriesFormat{GlossaryEntriesFormatTSV, GlossaryEntriesFormatCSV} {
+		encode := entries.TSV
+		if format == GlossaryEntriesFormatCSV {
+			encode = entries.CSV
+		}
+		data, err := encode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		parsed, err := ParseGlossaryEntries(data, format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(parsed) != len(entries) {
+			t.Fatalf("%s: expected %d entries, got %d", format, len(entries), len(parsed))
+		}
+		for source, target := range entries {
+			if parsed[source] != target {
+				t.Fatalf("%s: entry %q has not been restored: %q", format, source, parsed[source])
+			}
+		}
+	}
+	if _, err := (GlossaryEntries{"a\tb": "c"}).TSV(); err == nil {
+		t.Fatal("expected error for entry containing a tab")
+	}
+}
+
+// TestGlossaryApiFunctions tests the whole lifecycle of a glossary.
+func TestGlossaryApiFunctions(t *testing.T) {
+	client, _ := newFakeClient(t)
+	glossary, err := client.CreateGlossary(&GlossaryCreateRequest{
+		Name:       "Test",
+		SourceLang: LangDE,
+		TargetLang: LangEN,
+		Entries:    GlossaryEntries{"Hallo": "Hello", "Welt": "World"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if glossary.GlossaryId == "" || glossary.EntryCount != 2 || glossary.CreationTime.IsZero() {
+		t.Fatalf("unexpected glossary: %+v", glossary)
+	}
+	glossaries, err := client.ListGlossaries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(glossaries) != 1 || glossaries[0].GlossaryId != glossary.GlossaryId {
+		t.Fatalf("unexpected glossaries: %+v", glossaries)
+	}
+	entries, err := client.GetGlossaryEntries(glossary.GlossaryId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries["Hallo"] != "Hello" {
+		t.Fatalf("unexpected glossary entries: %+v", entries)
+	}
+	if err = client.DeleteGlossary(glossary.GlossaryId); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = client.GetGlossary(glossary.GlossaryId); err == nil {
+		t.Fatal("deleted glossary has been found")
+	}
+	pairs, err := client.GetGlossaryLanguagePairs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pairs) == 0 {
+		t.Fatal("no glossary language pairs have been returned")
+	}
+}
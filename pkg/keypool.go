@@ -0,0 +1,456 @@
+package deeplclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultKeyPoolRefreshInterval is the default time after which the usage of a pooled key is retrieved again
+	defaultKeyPoolRefreshInterval = 5 * time.Minute
+	// defaultKeyCooldown is the default time a pooled key is skipped after a quota or auth error
+	defaultKeyCooldown = time.Hour
+	// defaultKeyPoolDocumentTTL is the default time after which the key which uploaded a document is forgotten
+	defaultKeyPoolDocumentTTL = 24 * time.Hour
+	// authKeyIdSuffixLength is the amount of characters of an auth key contained in its ID
+	authKeyIdSuffixLength = 4
+)
+
+// KeySelection is the strategy of a key pool for choosing the auth key of a request.
+type KeySelection int
+
+const (
+	// SelectRoundRobin uses the available keys in turn.
+	SelectRoundRobin KeySelection = iota
+	// SelectMostRemaining uses the available key with the most remaining characters. The usage of the keys is
+	// retrieved periodically and the characters sent in between are tracked locally.
+	SelectMostRemaining
+)
+
+// AuthKeyId returns the ID of the auth key, which is used to report which key of a key pool served a request. It only
+// contains the last four characters of the key (without the suffix of free keys), so it can be logged safely.
+func AuthKeyId(authKey string) string {
+	authKey = strings.TrimSuffix(authKey, freeAuthKeySuffix)
+	if len(authKey) < 2*authKeyIdSuffixLength {
+		return "****"
+	}
+	return "****" + authKey[len(authKey)-authKeyIdSuffixLength:]
+}
+
+// KeyPoolOptions configures a KeyPool.
+type KeyPoolOptions struct {
+	// Selection is the strategy for choosing the auth key of a request. Defaults to SelectRoundRobin.
+	Selection KeySelection
+	// RefreshInterval is the time after which the usage of a key is retrieved again if SelectMostRemaining is used.
+	// Defaults to five minutes.
+	RefreshInterval time.Duration
+	// Cooldown is the time a key is skipped after it failed with an exceeded quota or a failed authentication.
+	// Defaults to one hour.
+	Cooldown time.Duration
+	// DocumentTTL is the time after which the key which uploaded a document is forgotten if the translated document
+	// has been neither downloaded nor seen failed, e.g. because it has been abandoned. Defaults to 24 hours.
+	DocumentTTL time.Duration
+	// OnServed is called with the ID of the key (see AuthKeyId) and the endpoint (e.g. "translate" or
+	// "document/:id") whenever a request has been served successfully.
+	OnServed func(keyId, endpoint string)
+}
+
+// pooledKey is an auth key of a key pool.
+type pooledKey struct {
+	id      string
+	authKey string
+	// unavailableUntil is the time until which the key is skipped after a quota or auth error
+	unavailableUntil time.Time
+	// refreshed is the time of the last usage refresh
+	refreshed time.Time
+	// remaining is the amount of remaining characters reported by the last usage refresh minus the characters sent
+	// since then
+	remaining int64
+}
+
+// keyBinding binds the requests made with a context to a single key of a key pool. A binding is safe for concurrent
+// use, as the requests made with a context may be sent concurrently (e.g. by TranslateBatchWithContext).
+type keyBinding struct {
+	// id is the ID of the key requested by WithPooledKey, which is resolved by the first request
+	id string
+
+	mutex sync.Mutex
+	// key is the key used for all requests, which is set by the first successful request if nil and id is empty
+	key *pooledKey
+}
+
+// resolve returns the key of the binding, which is looked up in the pool if the binding has been created by
+// WithPooledKey. Nil is returned if the binding has not been bound to a key yet. An error is returned if the pool does
+// not contain the requested key.
+func (binding *keyBinding) resolve(pool *KeyPool) (*pooledKey, error) {
+	binding.mutex.Lock()
+	defer binding.mutex.Unlock()
+	if binding.key == nil && binding.id != "" {
+		binding.key = pool.keyById(binding.id)
+		if binding.key == nil {
+			return nil, fmt.Errorf("key pool does not contain auth key %s", binding.id)
+		}
+	}
+	return binding.key, nil
+}
+
+// boundKey returns the key of the binding, which is nil if the binding has not been bound to a key yet.
+func (binding *keyBinding) boundKey() *pooledKey {
+	binding.mutex.Lock()
+	defer binding.mutex.Unlock()
+	return binding.key
+}
+
+// bind binds the binding to the key unless it has already been bound to a key.
+func (binding *keyBinding) bind(key *pooledKey) {
+	binding.mutex.Lock()
+	defer binding.mutex.Unlock()
+	if binding.key == nil {
+		binding.key = key
+	}
+}
+
+// WithPooledKey returns a copy of the context whose requests are sent with the auth key of the key pool which has the
+// given ID (see AuthKeyId), e.g. to access glossaries or documents of a specific account which have not been created
+// by the client. Requests fail if the pool of the client does not contain such a key. The context has no effect on
+// clients without key pool.
+func WithPooledKey(ctx context.Context, keyId string) context.Context {
+	return context.WithValue(ctx, keyBindingContextKey{}, &keyBinding{id: keyId})
+}
+
+// keyBindingContextKey is the key of the key binding within a context.
+type keyBindingContextKey struct{}
+
+// KeyPool spreads the requests of a client over several auth keys, e.g. of different billing accounts. If a request
+// fails because the quota of its key has been exceeded or the key has been rejected, the key is skipped for a cooldown
+// period and the request is sent again with the next available key. If no key is available, the key becoming
+// available first is used anyway, so requests fail with the error of the API server.
+//
+// Documents and glossaries can only be accessed with the key which created them, so all requests concerning documents
+// and glossaries created by the client are sent with the same key. Other documents and glossaries, as well as the
+// glossaries listed by ListGlossaries, have to be accessed with the key of their account using WithPooledKey. The
+// usage reported by the client is the sum of the usage of all keys. Streamed uploads are never sent again with another
+// key. A pool is attached to a client with WithKeyPool and is safe for concurrent use.
+type KeyPool struct {
+	options KeyPoolOptions
+	keys    []*pooledKey
+	free    bool
+	// usage retrieves the current usage from the API server
+	usage func(ctx context.Context) (*UsageResponse, error)
+
+	mutex sync.Mutex
+	// next is the offset of the key considered first by the next selection
+	next int
+	// resources maps the documents and glossaries created by the client to the keys which created them
+	resources map[poolResource]resourcePin
+}
+
+// poolResource identifies a document or glossary, which can only be accessed with the key which created it.
+type poolResource struct {
+	// kind is the API function of the resource, e.g. "document" or "glossaries"
+	kind string
+	id   string
+}
+
+// resourcePin records the key which created a document or glossary.
+type resourcePin struct {
+	key *pooledKey
+	// pinned is the time the resource has been created
+	pinned time.Time
+}
+
+// NewKeyPool creates a new key pool for the given auth keys and options, which are validated. All keys have to belong
+// to the same plan (see IsFreeAuthKey), as they share the endpoint of the client.
+func NewKeyPool(authKeys []string, options KeyPoolOptions) (*KeyPool, error) {
+	switch {
+	case len(authKeys) == 0:
+		return nil, errors.New("key pool requires at least one auth key")
+	case options.Selection != SelectRoundRobin && options.Selection != SelectMostRemaining:
+		return nil, fmt.Errorf("unknown key selection %d", options.Selection)
+	case options.RefreshInterval < 0:
+		return nil, errors.New("refresh interval of key pool cannot be negative")
+	case options.Cooldown < 0:
+		return nil, errors.New("cooldown of key pool cannot be negative")
+	case options.DocumentTTL < 0:
+		return nil, errors.New("document TTL of key pool cannot be negative")
+	}
+	if options.RefreshInterval == 0 {
+		options.RefreshInterval = defaultKeyPoolRefreshInterval
+	}
+	if options.Cooldown == 0 {
+		options.Cooldown = defaultKeyCooldown
+	}
+	if options.DocumentTTL == 0 {
+		options.DocumentTTL = defaultKeyPoolDocumentTTL
+	}
+	pool := &KeyPool{
+		options:   options,
+		free:      IsFreeAuthKey(authKeys[0]),
+		resources: map[poolResource]resourcePin{},
+	}
+	ids := map[string]bool{}
+	for _, authKey := range authKeys {
+		if authKey == "" {
+			return nil, errors.New("auth key cannot be empty")
+		}
+		if IsFreeAuthKey(authKey) != pool.free {
+			return nil, errors.New("auth keys of a key pool have to belong to the same plan")
+		}
+		id := AuthKeyId(authKey)
+		if ids[id] {
+			return nil, fmt.Errorf("auth keys of a key pool have to be distinguishable, but several keys have ID %s",
+				id)
+		}
+		ids[id] = true
+		pool.keys = append(pool.keys, &pooledKey{id: id, authKey: authKey})
+	}
+	return pool, nil
+}
+
+// WithKeyPool sends the requests of the client with the auth keys of the pool instead of the auth key passed to
+// NewClient, which only determines the endpoint and therefore has to belong to the same plan as the keys of the pool.
+// A pool can only be attached to a single client.
+func WithKeyPool(pool *KeyPool) Option {
+	return func(client *Client) error {
+		if pool == nil {
+			return errors.New("key pool cannot be nil")
+		}
+		if IsFreeAuthKey(string(client.AuthKey)) != pool.free {
+			return errors.New("auth keys of the key pool have to belong to the same plan as the auth key of the client")
+		}
+		client.keys = pool
+		client.attachments = append(client.attachments, attachment{
+			attach: func() error {
+				return pool.attach(client.getUsage)
+			},
+			detach: pool.detach,
+		})
+		return nil
+	}
+}
+
+// attach binds the pool to the client whose usage of a single key is retrieved by the given function.
+func (pool *KeyPool) attach(usage func(ctx context.Context) (*UsageResponse, error)) error {
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+	if pool.usage != nil {
+		return errors.New("key pool is already attached to a client")
+	}
+	pool.usage = usage
+	return nil
+}
+
+// detach releases the pool from the client it has been attached to.
+func (pool *KeyPool) detach() {
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+	pool.usage = nil
+}
+
+// pooledKeyIds returns the IDs of all keys of the pool, which is nil if the pool is nil.
+func (pool *KeyPool) pooledKeyIds() []string {
+	if pool == nil {
+		return nil
+	}
+	ids := make([]string, len(pool.keys))
+	for i, key := range pool.keys {
+		ids[i] = key.id
+	}
+	return ids
+}
+
+// bound returns whether the requests of the context are bound to a single key.
+func bound(ctx context.Context) bool {
+	_, ok := ctx.Value(keyBindingContextKey{}).(*keyBinding)
+	return ok
+}
+
+// acquire selects the key for the next request, which is the key bound to the context, if any. Keys which have
+// already been tried are skipped. Nil is returned if no further key is available or the pool is nil. An error is
+// returned if the context is bound to a key which is not contained in the pool.
+func (pool *KeyPool) acquire(ctx context.Context, tried map[*pooledKey]bool, characters int) (*pooledKey, error) {
+	if pool == nil {
+		return nil, nil
+	}
+	if binding, ok := ctx.Value(keyBindingContextKey{}).(*keyBinding); ok {
+		key, err := binding.resolve(pool)
+		if err != nil {
+			return nil, err
+		}
+		if key != nil {
+			if tried[key] {
+				return nil, nil
+			}
+			return key, nil
+		}
+	}
+	if pool.options.Selection == SelectMostRemaining {
+		pool.refreshUsage(ctx)
+	}
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+	now := time.Now()
+	selected := -1
+	for i := range pool.keys {
+		index := (pool.next + i) % len(pool.keys)
+		key := pool.keys[index]
+		if tried[key] || now.Before(key.unavailableUntil) {
+			continue
+		}
+		if selected < 0 ||
+			pool.options.Selection == SelectMostRemaining && key.remaining > pool.keys[selected].remaining {
+			selected = index
+		}
+	}
+	if selected < 0 && len(tried) == 0 {
+		// all keys are unavailable, so the key becoming available first is used anyway
+		for index, key := range pool.keys {
+			if selected < 0 || key.unavailableUntil.Before(pool.keys[selected].unavailableUntil) {
+				selected = index
+			}
+		}
+	}
+	if selected < 0 {
+		return nil, nil
+	}
+	// the next selection starts after the selected key, so round robin continues with the following key
+	pool.next = (selected + 1) % len(pool.keys)
+	pool.keys[selected].remaining -= int64(characters)
+	return pool.keys[selected], nil
+}
+
+// keyById returns the key with the given ID or nil if the pool does not contain such a key.
+func (pool *KeyPool) keyById(id string) *pooledKey {
+	for _, key := range pool.keys {
+		if key.id == id {
+			return key
+		}
+	}
+	return nil
+}
+
+// refreshUsage retrieves the usage of all available keys whose usage is outdated.
+func (pool *KeyPool) refreshUsage(ctx context.Context) {
+	pool.mutex.Lock()
+	now := time.Now()
+	var due []*pooledKey
+	for _, key := range pool.keys {
+		if !now.Before(key.unavailableUntil) && now.Sub(key.refreshed) >= pool.options.RefreshInterval {
+			// the refresh is claimed, so concurrent selections do not retrieve the usage again
+			key.refreshed = now
+			due = append(due, key)
+		}
+	}
+	usage := pool.usage
+	pool.mutex.Unlock()
+	for _, key := range due {
+		resp, err := usage(context.WithValue(ctx, keyBindingContextKey{}, &keyBinding{key: key}))
+		if err != nil {
+			pool.markUnavailable(key, err)
+			continue
+		}
+		pool.mutex.Lock()
+		key.remaining = resp.Remaining()
+		pool.mutex.Unlock()
+	}
+}
+
+// markUnavailable lets the key cool down if the error has been caused by an exceeded quota or a failed
+// authentication and returns whether this is the case.
+func (pool *KeyPool) markUnavailable(key *pooledKey, err error) bool {
+	if !errors.Is(err, ErrQuotaExceeded) && !errors.Is(err, ErrAuthFailed) {
+		return false
+	}
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+	key.unavailableUntil = time.Now().Add(pool.options.Cooldown)
+	return true
+}
+
+// failover returns whether the request, which failed with the given error, may be sent again with another key.
+func (pool *KeyPool) failover(ctx context.Context, key *pooledKey, err error) bool {
+	if pool == nil || key == nil || !pool.markUnavailable(key, err) {
+		return false
+	}
+	// requests bound to a key cannot be sent with another one
+	binding, ok := ctx.Value(keyBindingContextKey{}).(*keyBinding)
+	return !ok || binding.boundKey() == nil
+}
+
+// served binds the context to the key, if requested, and reports the key which served the request.
+func (pool *KeyPool) served(ctx context.Context, key *pooledKey, endpoint string) {
+	if pool == nil || key == nil {
+		return
+	}
+	if binding, ok := ctx.Value(keyBindingContextKey{}).(*keyBinding); ok {
+		binding.bind(key)
+	}
+	if pool.options.OnServed != nil {
+		pool.options.OnServed(key.id, endpoint)
+	}
+}
+
+// bindCreation returns a copy of the context whose requests are bound to the key which served the first of them, so
+// the created document or glossary can be recorded by pin.
+func (pool *KeyPool) bindCreation(ctx context.Context) context.Context {
+	if pool == nil || bound(ctx) {
+		return ctx
+	}
+	return context.WithValue(ctx, keyBindingContextKey{}, &keyBinding{})
+}
+
+// pin records the key which created the document or glossary within the context returned by bindCreation. Documents
+// pinned longer than the document TTL are forgotten, as they are never unpinned if they have been abandoned.
+func (pool *KeyPool) pin(ctx context.Context, kind, id string) {
+	if pool == nil {
+		return
+	}
+	binding, ok := ctx.Value(keyBindingContextKey{}).(*keyBinding)
+	if !ok {
+		return
+	}
+	key := binding.boundKey()
+	if key == nil {
+		return
+	}
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+	now := time.Now()
+	for resource, pin := range pool.resources {
+		if resource.kind == documentTranslateFunctionUri && now.Sub(pin.pinned) >= pool.options.DocumentTTL {
+			delete(pool.resources, resource)
+		}
+	}
+	pool.resources[poolResource{kind, id}] = resourcePin{key: key, pinned: now}
+}
+
+// resourceContext returns a copy of the context whose requests are bound to the key which created the document or
+// glossary. The context is returned unchanged if the resource has not been created by the client or the context is
+// already bound to a key.
+func (pool *KeyPool) resourceContext(ctx context.Context, kind, id string) context.Context {
+	if pool == nil || bound(ctx) {
+		return ctx
+	}
+	pool.mutex.Lock()
+	pin, ok := pool.resources[poolResource{kind, id}]
+	pool.mutex.Unlock()
+	if !ok {
+		return ctx
+	}
+	return context.WithValue(ctx, keyBindingContextKey{}, &keyBinding{key: pin.key})
+}
+
+// unpin forgets the key which created the document or glossary after it has been downloaded, has failed or has been
+// deleted.
+func (pool *KeyPool) unpin(kind, id string) {
+	if pool == nil {
+		return
+	}
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+	delete(pool.resources, poolResource{kind, id})
+}
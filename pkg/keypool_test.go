@@ -0,0 +1,430 @@
+package deeplclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/PineiroHosting/deeplgobindings/pkg/deepltest"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// poolKeys contains auth keys for key pool tests, whose IDs are "****0001" to "****0003".
+var poolKeys = []string{
+	"00000000-0000-0000-0000-000000000001",
+	"00000000-0000-0000-0000-000000000002",
+	"00000000-0000-0000-0000-000000000003",
+}
+
+// keyAccounts simulates a separate account for each auth key of a key pool.
+type keyAccounts struct {
+	mutex sync.Mutex
+	// exhausted contains the keys whose quota has been exceeded
+	exhausted map[string]bool
+	// usage contains the character count and limit of each key
+	usage map[string][2]int64
+	// keys contains the IDs of the keys of all requests sent to the server
+	keys []string
+}
+
+// middleware answers the requests of exhausted keys with an exceeded quota and the usage requests with the usage of
+// their key.
+func (accounts *keyAccounts) middleware(next RoundTripFunc) RoundTripFunc {
+	return func(req *http.Request) (*http.Response, error) {
+		authKey := strings.TrimPrefix(req.Header.Get("Authorization"), "DeepL-Auth-Key ")
+		accounts.mutex.Lock()
+		accounts.keys = append(accounts.keys, AuthKeyId(authKey))
+		exhausted := accounts.exhausted[authKey]
+		usage, ok := accounts.usage[authKey]
+		accounts.mutex.Unlock()
+		respond := func(statusCode int, body string) (*http.Response, error) {
+			return &http.Response{StatusCode: statusCode, Header: http.Header{},
+				Body: io.NopCloser(strings.NewReader(body)), Request: req}, nil
+		}
+		switch {
+		case exhausted:
+			return respond(456, `{"message":"Quota Exceeded"}`)
+		case ok && strings.HasSuffix(req.URL.Path, "/usage"):
+			return respond(http.StatusOK, fmt.Sprintf(`{"character_count":%d,"character_limit":%d}`, usage[0],
+				usage[1]))
+		}
+		return next(req)
+	}
+}
+
+// servedKeys returns the IDs of the keys of all requests sent so far and resets them.
+func (accounts *keyAccounts) servedKeys() []string {
+	accounts.mutex.Lock()
+	defer accounts.mutex.Unlock()
+	keys := accounts.keys
+	accounts.keys = nil
+	return keys
+}
+
+// newPoolClient creates a client using a new local fake server and a key pool of the given keys, whose accounts are
+// simulated by the returned key accounts.
+func newPoolClient(t *testing.T, authKeys []string, options KeyPoolOptions, clientOptions ...Option) (*Client,
+	*deepltest.Server, *keyAccounts) {
+	pool, err := NewKeyPool(authKeys, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	accounts := &keyAccounts{exhausted: map[string]bool{}, usage: map[string][2]int64{}}
+	client, server := newFakeClient(t, append([]Option{WithKeyPool(pool), WithMiddleware(accounts.middleware)},
+		clientOptions...)...)
+	return client, server, accounts
+}
+
+// TestAuthKeyId tests whether the IDs of auth keys do not reveal the keys.
+func TestAuthKeyId(t *testing.T) {
+	tests := map[string]string{
+		"00000000-0000-0000-0000-00000000abcd:fx": "****abcd",
+		"00000000-0000-0000-0000-00000000abcd":    "****abcd",
+		"short":                                   "****",
+	}
+	for authKey, expected := range tests {
+		if id := AuthKeyId(authKey); id != expected {
+			t.Errorf("unexpected ID of %s: %s", authKey, id)
+		}
+	}
+}
+
+// TestKeyPoolRoundRobin tests whether the requests are spread evenly over all keys and the serving keys are reported.
+func TestKeyPoolRoundRobin(t *testing.T) {
+	var mutex sync.Mutex
+	var served []string
+	client, _, accounts := newPoolClient(t, poolKeys, KeyPoolOptions{
+		OnServed: func(keyId, endpoint string) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			served = append(served, keyId+" "+endpoint)
+		},
+	})
+	for i := 0; i < 4; i++ {
+		if _, err := client.Translate(&TranslationRequest{Text: "Hallo", TargetLang: LangENGB}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected := []string{"****0001", "****0002", "****0003", "****0001"}
+	if keys := accounts.servedKeys(); fmt.Sprint(keys) != fmt.Sprint(expected) {
+		t.Fatalf("unexpected keys: %v", keys)
+	}
+	if len(served) != 4 || served[1] != "****0002 translate" {
+		t.Fatalf("unexpected served keys: %v", served)
+	}
+
+	// the key after an unavailable key is not used twice in a row
+	client, _, accounts = newPoolClient(t, poolKeys, KeyPoolOptions{})
+	accounts.exhausted[poolKeys[1]] = true
+	for i := 0; i < 6; i++ {
+		if _, err := client.Translate(&TranslationRequest{Text: "Hallo", TargetLang: LangENGB}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected = []string{"****0001", "****0002", "****0003", "****0001", "****0003", "****0001", "****0003"}
+	if keys := accounts.servedKeys(); fmt.Sprint(keys) != fmt.Sprint(expected) {
+		t.Fatalf("unexpected keys: %v", keys)
+	}
+}
+
+// TestKeyPoolFailover tests whether requests are sent again with the next key if the quota of a key is exceeded or
+// a key is rejected.
+func TestKeyPoolFailover(t *testing.T) {
+	client, server, accounts := newPoolClient(t, poolKeys, KeyPoolOptions{})
+	accounts.exhausted[poolKeys[0]] = true
+	server.SetAuthKey(poolKeys[2])
+	req := &TranslationRequest{Text: "Hallo", TargetLang: LangENGB}
+	if _, err := client.Translate(req); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"****0001", "****0002", "****0003"}
+	if keys := accounts.servedKeys(); fmt.Sprint(keys) != fmt.Sprint(expected) {
+		t.Fatalf("unexpected keys: %v", keys)
+	}
+	// failed keys are skipped during their cooldown
+	for i := 0; i < 2; i++ {
+		if _, err := client.Translate(req); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected = []string{"****0003", "****0003"}
+	if keys := accounts.servedKeys(); fmt.Sprint(keys) != fmt.Sprint(expected) {
+		t.Fatalf("unexpected keys: %v", keys)
+	}
+	// the error of the last key is returned if all keys fail
+	accounts.exhausted[poolKeys[2]] = true
+	if _, err := client.Translate(req); !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("expected quota exceeded error, got: %v", err)
+	}
+	if keys := accounts.servedKeys(); len(keys) != 1 {
+		t.Fatalf("expected a single request, got: %v", keys)
+	}
+}
+
+// TestKeyPoolMostRemaining tests whether the requests are sent with the key with the most remaining characters.
+func TestKeyPoolMostRemaining(t *testing.T) {
+	client, _, accounts := newPoolClient(t, poolKeys[:2], KeyPoolOptions{
+		Selection:       SelectMostRemaining,
+		RefreshInterval: time.Hour,
+	})
+	accounts.usage[poolKeys[0]] = [2]int64{900, 1000}
+	accounts.usage[poolKeys[1]] = [2]int64{0, 150}
+	req := &TranslationRequest{Text: strings.Repeat("a", 100), TargetLang: LangENGB}
+	for i := 0; i < 2; i++ {
+		if _, err := client.Translate(req); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the usage of both keys is retrieved first, then the characters are tracked locally
+	expected := []string{"****0001", "****0002", "****0002", "****0001"}
+	if keys := accounts.servedKeys(); fmt.Sprint(keys) != fmt.Sprint(expected) {
+		t.Fatalf("unexpected keys: %v", keys)
+	}
+}
+
+// TestKeyPoolDocuments tests whether documents are always accessed with the key which uploaded them.
+func TestKeyPoolDocuments(t *testing.T) {
+	client, _, accounts := newPoolClient(t, poolKeys, KeyPoolOptions{})
+	result, err := client.TranslateDocumentWithContext(context.Background(), &DocumentTranslationStartRequest{
+		TargetLang: LangDE,
+		File:       []byte("Hello world!"),
+		Filename:   "hello.txt",
+	}, fastPolling)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result) == 0 {
+		t.Fatal("expected translated document")
+	}
+	keys := accounts.servedKeys()
+	for _, key := range keys {
+		if key != "****0001" {
+			t.Fatalf("expected all requests with the first key, got: %v", keys)
+		}
+	}
+	if len(keys) < 3 {
+		t.Fatalf("expected at least upload, status check and download, got: %v", keys)
+	}
+}
+
+// TestKeyPoolDocumentPins tests whether the keys which uploaded documents are forgotten once the translation failed or
+// the documents have been abandoned.
+func TestKeyPoolDocumentPins(t *testing.T) {
+	client, server, _ := newPoolClient(t, poolKeys, KeyPoolOptions{DocumentTTL: time.Millisecond})
+	pinned := func(documentId string) bool {
+		client.keys.mutex.Lock()
+		defer client.keys.mutex.Unlock()
+		_, ok := client.keys.resources[poolResource{documentTranslateFunctionUri, documentId}]
+		return ok
+	}
+	req := &DocumentTranslationStartRequest{TargetLang: LangDE, File: []byte("Hello world!"), Filename: "hello.txt"}
+	abandoned, err := client.StartDocumentTranslate(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !pinned(abandoned.DocumentId) {
+		t.Fatal("expected uploaded document to be pinned")
+	}
+	time.Sleep(2 * time.Millisecond)
+	server.SetDocumentError("Translation failed")
+	failed, err := client.StartDocumentTranslate(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pinned(abandoned.DocumentId) {
+		t.Fatal("expected abandoned document to be forgotten")
+	}
+	if err = waitForDocumentTranslation(context.Background(), client, failed, fastPolling); err == nil {
+		t.Fatal("expected document translation error")
+	}
+	if pinned(failed.DocumentId) {
+		t.Fatal("expected failed document to be forgotten")
+	}
+}
+
+// TestKeyPoolGlossaries tests whether glossaries created by the client are always accessed with the key which created
+// them and other glossaries can be accessed with a specific key.
+func TestKeyPoolGlossaries(t *testing.T) {
+	client, _, accounts := newPoolClient(t, poolKeys, KeyPoolOptions{})
+	glossary, err := client.CreateGlossary(&GlossaryCreateRequest{
+		Name:       "Test",
+		SourceLang: LangDE,
+		TargetLang: LangEN,
+		Entries:    GlossaryEntries{"Hallo": "Hello"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = client.GetGlossary(glossary.GlossaryId); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = client.Translate(&TranslationRequest{Text: "Hallo", SourceLang: LangDE, TargetLang: LangENGB,
+		GlossaryId: ApiLang(glossary.GlossaryId)}); err != nil {
+		t.Fatal(err)
+	}
+	if err = client.DeleteGlossary(glossary.GlossaryId); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"****0001", "****0001", "****0001", "****0001"}
+	if keys := accounts.servedKeys(); fmt.Sprint(keys) != fmt.Sprint(expected) {
+		t.Fatalf("unexpected keys: %v", keys)
+	}
+
+	ctx := WithPooledKey(context.Background(), "****0003")
+	if _, err = client.ListGlossariesWithContext(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if keys := accounts.servedKeys(); fmt.Sprint(keys) != "[****0003]" {
+		t.Fatalf("unexpected keys: %v", keys)
+	}
+	if _, err = client.ListGlossariesWithContext(WithPooledKey(context.Background(), "****9999")); err == nil {
+		t.Fatal("expected error for unknown key")
+	}
+}
+
+// TestKeyPoolBoundBatch tests whether the requests of a batch sent concurrently with a context bound by WithPooledKey
+// are all sent with the requested key.
+func TestKeyPoolBoundBatch(t *testing.T) {
+	client, _, accounts := newPoolClient(t, poolKeys, KeyPoolOptions{}, WithBatchConcurrency(4))
+	texts := make([]string, 4*maxTextsPerRequest)
+	for i := range texts {
+		texts[i] = fmt.Sprintf("Hallo %d", i)
+	}
+	ctx := WithPooledKey(context.Background(), "****0002")
+	translations, err := client.TranslateBatchWithContext(ctx, &TranslationRequest{TargetLang: LangENGB}, texts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(translations) != len(texts) {
+		t.Fatalf("expected %d translations, got %d", len(texts), len(translations))
+	}
+	keys := accounts.servedKeys()
+	for _, key := range keys {
+		if key != "****0002" {
+			t.Fatalf("expected all requests with the second key, got: %v", keys)
+		}
+	}
+	if len(keys) != 4 {
+		t.Fatalf("expected 4 requests, got: %v", keys)
+	}
+}
+
+// TestKeyPoolUsage tests whether the usage of all keys is summed up and the usage retrieved internally for selecting
+// keys is not reported to the metrics collector.
+func TestKeyPoolUsage(t *testing.T) {
+	metrics := NewPrometheusMetrics()
+	client, _, accounts := newPoolClient(t, poolKeys[:2], KeyPoolOptions{
+		Selection:       SelectMostRemaining,
+		RefreshInterval: time.Hour,
+	}, WithMetrics(metrics))
+	accounts.usage[poolKeys[0]] = [2]int64{900, 1000}
+	accounts.usage[poolKeys[1]] = [2]int64{0, 150}
+	if _, err := client.Translate(&TranslationRequest{Text: "Hallo", TargetLang: LangENGB}); err != nil {
+		t.Fatal(err)
+	}
+	var exposition strings.Builder
+	if _, err := metrics.WriteTo(&exposition); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(exposition.String(), "deepl_usage_character_count") {
+		t.Fatal("usage of a single key has been reported")
+	}
+	usage, err := client.GetUsage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usage.CharacterCount != 900 || usage.CharacterLimit != 1150 || usage.Remaining() != 250 {
+		t.Fatalf("unexpected usage: %+v", usage)
+	}
+	exposition.Reset()
+	if _, err = metrics.WriteTo(&exposition); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(exposition.String(), "deepl_usage_character_limit 1150") {
+		t.Fatalf("summed usage has not been reported: %s", exposition.String())
+	}
+	usage, err = client.GetUsageWithContext(WithPooledKey(context.Background(), "****0002"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usage.CharacterLimit != 150 {
+		t.Fatalf("unexpected usage of single key: %+v", usage)
+	}
+}
+
+// TestKeyPoolUsageFailedKeys tests whether keys failing to report their usage because they have been rejected or
+// their quota has been exceeded are skipped, so the quota guard keeps working with the remaining keys.
+func TestKeyPoolUsageFailedKeys(t *testing.T) {
+	guard := newQuotaGuard(t, QuotaGuardOptions{BudgetPercent: 100, RefreshInterval: time.Hour})
+	client, server, accounts := newPoolClient(t, poolKeys, KeyPoolOptions{}, WithQuotaGuard(guard))
+	server.SetAuthKey(poolKeys[1])
+	accounts.exhausted[poolKeys[2]] = true
+	accounts.usage[poolKeys[1]] = [2]int64{100, 1000}
+	if _, err := client.Translate(&TranslationRequest{Text: "Hallo", TargetLang: LangENGB}); err != nil {
+		t.Fatal(err)
+	}
+	usage, err := client.GetUsage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usage.CharacterCount != 100 || usage.CharacterLimit != 1000 {
+		t.Fatalf("unexpected usage: %+v", usage)
+	}
+	// the failed keys are skipped by the following requests
+	accounts.servedKeys()
+	if _, err = client.Translate(&TranslationRequest{Text: "Hallo", TargetLang: LangENGB}); err != nil {
+		t.Fatal(err)
+	}
+	if keys := accounts.servedKeys(); fmt.Sprint(keys) != "[****0002]" {
+		t.Fatalf("unexpected keys: %v", keys)
+	}
+
+	// an error is returned if no key reports its usage
+	accounts.exhausted[poolKeys[1]] = true
+	if _, err = client.GetUsage(); !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("expected quota exceeded error, got: %v", err)
+	}
+}
+
+// TestKeyPoolInvalidOptions tests whether invalid key pools are rejected.
+func TestKeyPoolInvalidOptions(t *testing.T) {
+	tests := []struct {
+		authKeys []string
+		options  KeyPoolOptions
+	}{
+		{nil, KeyPoolOptions{}},
+		{[]string{""}, KeyPoolOptions{}},
+		{[]string{poolKeys[0], poolKeys[1] + ":fx"}, KeyPoolOptions{}},
+		{[]string{poolKeys[0], "10000000-0000-0000-0000-000000000001"}, KeyPoolOptions{}},
+		{poolKeys, KeyPoolOptions{Selection: 2}},
+		{poolKeys, KeyPoolOptions{RefreshInterval: -time.Second}},
+		{poolKeys, KeyPoolOptions{Cooldown: -time.Second}},
+		{poolKeys, KeyPoolOptions{DocumentTTL: -time.Second}},
+	}
+	for i, test := range tests {
+		if _, err := NewKeyPool(test.authKeys, test.options); err == nil {
+			t.Errorf("expected error for key pool %d", i)
+		}
+	}
+	pool, err := NewKeyPool(poolKeys, KeyPoolOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = NewClient("test:fx", WithKeyPool(pool)); err == nil {
+		t.Fatal("expected error for key pool of another plan")
+	}
+	// the pool must not be attached to a client whose creation failed
+	if _, err = NewClient("test", WithKeyPool(pool), WithLogger(nil)); err == nil {
+		t.Fatal("expected error for invalid logger")
+	}
+	if _, err = NewClient("test", WithKeyPool(pool)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = NewClient("test", WithKeyPool(pool)); err == nil {
+		t.Fatal("expected error for key pool attached twice")
+	}
+}
@@ -0,0 +1,147 @@
+package deeplclient
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+const (
+	languagesFunctionUri = "languages"
+	languageTypeSource   = "source"
+	languageTypeTarget   = "target"
+)
+
+// Language represents a language supported by the DeepL API.
+type Language struct {
+	// Code is the language code which can be used in requests.
+	Code ApiLang `json:"language"`
+	// Name is the name of the language in English.
+	Name string `json:"name"`
+	// SupportsFormality indicates whether the formality can be set for this (target) language.
+	SupportsFormality bool `json:"supports_formality"`
+}
+
+// languageRegistry caches the languages supported by the DeepL API, so languages launched after the release of this
+// library can be used as well.
+type languageRegistry struct {
+	mutex  sync.RWMutex
+	source map[ApiLang]Language
+	target map[ApiLang]Language
+}
+
+// store replaces the cached languages of the given type.
+func (registry *languageRegistry) store(languageType string, languages []Language) {
+	cache := make(map[ApiLang]Language, len(languages))
+	for _, language := range languages {
+		cache[ApiLang(strings.ToUpper(language.Code.String()))] = language
+	}
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+	if languageType == languageTypeSource {
+		registry.source = cache
+	} else {
+		registry.target = cache
+	}
+}
+
+// lookup returns the cached source or target language with the given code.
+func (registry *languageRegistry) lookup(apiLang ApiLang, target bool) (language Language, ok bool) {
+	registry.mutex.RLock()
+	defer registry.mutex.RUnlock()
+	if target {
+		language, ok = registry.target[apiLang]
+	} else {
+		language, ok = registry.source[apiLang]
+	}
+	return
+}
+
+// cached returns whether any source or target languages are cached.
+func (registry *languageRegistry) cached(target bool) bool {
+	registry.mutex.RLock()
+	defer registry.mutex.RUnlock()
+	if target {
+		return len(registry.target) > 0
+	}
+	return len(registry.source) > 0
+}
+
+// GetSourceLanguages returns all languages which can be used as source language or an error if something went wrong.
+// The result is cached by the client.
+func (client *Client) GetSourceLanguages() ([]Language, error) {
+	return client.GetSourceLanguagesWithContext(context.Background())
+}
+
+// GetSourceLanguagesWithContext returns all languages which can be used as source language or an error if something
+// went wrong. The result is cached by the client. The request is cancelled as soon as the given context is done.
+func (client *Client) GetSourceLanguagesWithContext(ctx context.Context) ([]Language, error) {
+	return client.getLanguages(ctx, languageTypeSource)
+}
+
+// GetTargetLanguages returns all languages which can be used as target language or an error if something went wrong.
+// The result is cached by the client.
+func (client *Client) GetTargetLanguages() ([]Language, error) {
+	return client.GetTargetLanguagesWithContext(context.Background())
+}
+
+// GetTargetLanguagesWithContext returns all languages which can be used as target language or an error if something
+// went wrong. The result is cached by the client. The request is cancelled as soon as the given context is done.
+func (client *Client) GetTargetLanguagesWithContext(ctx context.Context) ([]Language, error) {
+	return client.getLanguages(ctx, languageTypeTarget)
+}
+
+// RefreshLanguages updates the cached source and target languages of the client.
+func (client *Client) RefreshLanguages() error {
+	return client.RefreshLanguagesWithContext(context.Background())
+}
+
+// RefreshLanguagesWithContext updates the cached source and target languages of the client. The requests are cancelled
+// as soon as the given context is done.
+func (client *Client) RefreshLanguagesWithContext(ctx context.Context) error {
+	if _, err := client.GetSourceLanguagesWithContext(ctx); err != nil {
+		return err
+	}
+	_, err := client.GetTargetLanguagesWithContext(ctx)
+	return err
+}
+
+// getLanguages executes the languages API function for the given type of languages and caches the result.
+func (client *Client) getLanguages(ctx context.Context, languageType string) (languages []Language, err error) {
+	values := &url.Values{}
+	values.Add("type", languageType)
+	var httpResp *http.Response
+	httpResp, err = client.doApiFunction(ctx, languagesFunctionUri, http.MethodGet, values)
+	if err != nil {
+		return
+	}
+	defer client.closeBody(httpResp.Body, "languages")
+	if err = json.NewDecoder(httpResp.Body).Decode(&languages); err != nil {
+		return nil, err
+	}
+	client.languages.store(languageType, languages)
+	return
+}
+
+// LangFromString tries to find and return the matching wrapped API language type. In contrast to the package function
+// LangFromString, it also considers the languages cached by the client (see RefreshLanguages), so languages launched
+// after the release of this library are found as well.
+func (client *Client) LangFromString(apiLangString string) (error, ApiLang) {
+	apiLang := ApiLang(strings.ToUpper(apiLangString))
+	if _, ok := client.languages.lookup(apiLang, false); ok {
+		return nil, apiLang
+	}
+	if _, ok := client.languages.lookup(apiLang, true); ok {
+		return nil, apiLang
+	}
+	return LangFromString(apiLangString)
+}
+
+// LanguageInfo returns the cached information about the given source or target language. The second return value is
+// false if the language is not cached (see RefreshLanguages).
+func (client *Client) LanguageInfo(apiLang ApiLang, target bool) (Language, bool) {
+	return client.languages.lookup(ApiLang(strings.ToUpper(apiLang.String())), target)
+}
@@ -0,0 +1,45 @@
+package deeplclient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestLanguagesRegistry tests whether languages returned by the API server are cached and found by the client.
+func TestLanguagesRegistry(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/languages" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		switch r.URL.Query().Get("type") {
+		case "source":
+			_, _ = w.Write([]byte(`[{"language":"DE","name":"German"},{"language":"XY","name":"New"}]`))
+		case "target":
+			_, _ = w.Write([]byte(`[{"language":"DE","name":"German","supports_formality":true},` +
+				`{"language":"XY","name":"New","supports_formality":false}]`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+	client := &Client{
+		Client:      server.Client(),
+		AuthKey:     []byte("test"),
+		EndpointUrl: server.URL + "/v2/",
+	}
+	if err, _ := client.LangFromString("XY"); err == nil {
+		t.Fatal("unknown language has been found before refreshing the languages")
+	}
+	if err := client.RefreshLanguages(); err != nil {
+		t.Fatal(err)
+	}
+	if err, lang := client.LangFromString("xy"); err != nil || lang != "XY" {
+		t.Fatalf("cached language has not been found: %v", err)
+	}
+	if language, ok := client.LanguageInfo(LangDE, true); !ok || !language.SupportsFormality ||
+		language.Name != "German" {
+		t.Fatalf("unexpected language information: %+v", language)
+	}
+}
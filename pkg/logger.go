@@ -0,0 +1,78 @@
+package deeplclient
+
+import (
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// redactedQueryParams contains the query parameters whose values are never logged, as they grant access to the
+// account or its documents.
+var redactedQueryParams = []string{"auth_key", "document_key"}
+
+// Logger receives debug messages of the client. The arguments are alternating keys and values, so *slog.Logger
+// implements the interface and can be passed to WithLogger directly.
+type Logger interface {
+	// Debug logs the message with the given key-value pairs.
+	Debug(msg string, args ...interface{})
+}
+
+// debug logs the message if a logger is configured.
+func (client *Client) debug(msg string, args ...interface{}) {
+	if client.logger != nil {
+		client.logger.Debug(msg, args...)
+	}
+}
+
+// logAttempt logs a single attempt of a request. Neither the auth key nor the body of the request, which may contain
+// document keys, is logged.
+func (client *Client) logAttempt(apiReq *apiRequest, attempt int, duration time.Duration, resp *http.Response,
+	err error) {
+	if client.logger == nil {
+		return
+	}
+	args := []interface{}{
+		"method", apiReq.method,
+		"url", redactUrl(apiReq.requestUrl),
+		"attempt", attempt,
+		"duration", duration,
+		"characters", apiReq.characters,
+	}
+	if apiReq.keyId != "" {
+		args = append(args, "key", apiReq.keyId)
+	}
+	if err != nil {
+		args = append(args, "error", err.Error())
+	} else {
+		args = append(args, "status", resp.StatusCode)
+	}
+	client.logger.Debug("deepl api request", args...)
+}
+
+// closeBody closes the body of the response to the named request and logs an error if it could not be closed.
+func (client *Client) closeBody(body io.Closer, request string) {
+	if err := body.Close(); err != nil {
+		client.debug("could not close response", "request", request, "error", err.Error())
+	}
+}
+
+// redactUrl replaces the values of query parameters containing secrets, so the URL can be logged.
+func redactUrl(rawUrl string) string {
+	parsed, err := url.Parse(rawUrl)
+	if err != nil {
+		return "(invalid url)"
+	}
+	parsed.User = nil
+	if parsed.RawQuery == "" {
+		return parsed.String()
+	}
+	query := parsed.Query()
+	for _, param := range redactedQueryParams {
+		if _, ok := query[param]; ok {
+			query.Set(param, "REDACTED")
+		}
+	}
+	parsed.RawQuery = query.Encode()
+	return parsed.String()
+}
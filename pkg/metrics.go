@@ -0,0 +1,266 @@
+package deeplclient
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// MetricsCollector receives metrics about the requests of a client. Implementations must be safe for concurrent use.
+type MetricsCollector interface {
+	// ObserveRequest is called after every attempt of a request to the given endpoint (e.g. "translate" or
+	// "document/:id"). The status code is zero if no response has been received.
+	ObserveRequest(endpoint string, statusCode int, duration time.Duration)
+	// ObserveCharacters is called after a successful translation with the amount of translated characters. The source
+	// language is the detected one if it has not been set in the request.
+	ObserveCharacters(sourceLang, targetLang ApiLang, characters int)
+	// ObserveUsage is called whenever the usage has been retrieved from the API server.
+	ObserveUsage(usage *UsageResponse)
+}
+
+// WithMetrics sets the collector receiving metrics about the requests of the client.
+func WithMetrics(collector MetricsCollector) Option {
+	return func(client *Client) error {
+		if collector == nil {
+			return errors.New("metrics collector cannot be nil")
+		}
+		client.metrics = collector
+		return nil
+	}
+}
+
+// endpointLabel returns the endpoint of the given API function URI with all IDs replaced by a placeholder, so the
+// amount of different endpoints stays small.
+func endpointLabel(uri string) string {
+	segments := strings.Split(uri, "/")
+	for i := 1; i < len(segments); i++ {
+		switch segments[i] {
+		case documentTranslateResultFunctionSubUri, glossaryEntriesFunctionSubUri:
+		default:
+			segments[i] = ":id"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// observeRequest reports a single attempt of a request to the metrics collector of the client. The response is nil if
+// the request failed without response.
+func (client *Client) observeRequest(apiReq *apiRequest, duration time.Duration, resp *http.Response) {
+	if client.metrics == nil {
+		return
+	}
+	statusCode := 0
+	if resp != nil {
+		statusCode = resp.StatusCode
+	}
+	client.metrics.ObserveRequest(apiReq.endpoint, statusCode, duration)
+}
+
+// observeCharacters reports the characters of the translated texts to the metrics collector of the client.
+func (client *Client) observeCharacters(sourceLang, targetLang ApiLang, texts []string, resp *TranslationResponse) {
+	if client.metrics == nil {
+		return
+	}
+	characters := map[ApiLang]int{}
+	for i, text := range texts {
+		detectedLang := sourceLang
+		if detectedLang == "" && i < len(resp.Translations) {
+			detectedLang = resp.Translations[i].DetectedSourceLanguage
+		}
+		characters[detectedLang] += utf8.RuneCountInString(text)
+	}
+	for lang, count := range characters {
+		client.metrics.ObserveCharacters(lang, targetLang, count)
+	}
+}
+
+// defaultLatencyBuckets contains the upper bounds (in seconds) of the latency histogram buckets.
+var defaultLatencyBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// histogram is a cumulative latency histogram.
+type histogram struct {
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+// PrometheusMetrics is a MetricsCollector which exposes the metrics in the Prometheus text exposition format. It
+// implements http.Handler, so it can be registered as scrape endpoint (e.g. at "/metrics") directly.
+//
+// The following metrics are exposed:
+//
+//	deepl_requests_total{endpoint,status}: counter of requests (status "error" if no response has been received)
+//	deepl_request_duration_seconds{endpoint}: histogram of the request latency
+//	deepl_characters_total{source_lang,target_lang}: counter of translated characters
+//	deepl_usage_character_count: gauge of the characters translated in the current billing period
+//	deepl_usage_character_limit: gauge of the character limit of the current billing period (if limited)
+//	deepl_usage_document_count, deepl_usage_document_limit: gauges of the documents (if limited)
+type PrometheusMetrics struct {
+	mutex      sync.Mutex
+	buckets    []float64
+	requests   map[[2]string]uint64
+	latencies  map[string]*histogram
+	characters map[[2]string]uint64
+	usage      *UsageResponse
+}
+
+// NewPrometheusMetrics creates a new collector using the given upper bounds (in seconds) of the latency histogram
+// buckets. Reasonable default buckets are used if none are given.
+func NewPrometheusMetrics(buckets ...float64) *PrometheusMetrics {
+	if len(buckets) == 0 {
+		buckets = defaultLatencyBuckets
+	}
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+	return &PrometheusMetrics{
+		buckets:    buckets,
+		requests:   map[[2]string]uint64{},
+		latencies:  map[string]*histogram{},
+		characters: map[[2]string]uint64{},
+	}
+}
+
+// ObserveRequest counts the request and records its latency.
+func (metrics *PrometheusMetrics) ObserveRequest(endpoint string, statusCode int, duration time.Duration) {
+	status := "error"
+	if statusCode != 0 {
+		status = strconv.Itoa(statusCode)
+	}
+	metrics.mutex.Lock()
+	defer metrics.mutex.Unlock()
+	metrics.requests[[2]string{endpoint, status}]++
+	latency, ok := metrics.latencies[endpoint]
+	if !ok {
+		latency = &histogram{counts: make([]uint64, len(metrics.buckets))}
+		metrics.latencies[endpoint] = latency
+	}
+	seconds := duration.Seconds()
+	for i, bound := range metrics.buckets {
+		if seconds <= bound {
+			latency.counts[i]++
+		}
+	}
+	latency.count++
+	latency.sum += seconds
+}
+
+// ObserveCharacters counts the translated characters of the language pair.
+func (metrics *PrometheusMetrics) ObserveCharacters(sourceLang, targetLang ApiLang, characters int) {
+	metrics.mutex.Lock()
+	defer metrics.mutex.Unlock()
+	metrics.characters[[2]string{sourceLang.String(), targetLang.String()}] += uint64(characters)
+}
+
+// ObserveUsage records the usage for the usage gauges.
+func (metrics *PrometheusMetrics) ObserveUsage(usage *UsageResponse) {
+	copied := *usage
+	metrics.mutex.Lock()
+	defer metrics.mutex.Unlock()
+	metrics.usage = &copied
+}
+
+// ServeHTTP writes all metrics in the Prometheus text exposition format.
+func (metrics *PrometheusMetrics) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_, _ = metrics.WriteTo(w)
+}
+
+// WriteTo writes all metrics in the Prometheus text exposition format to the writer. The output is sorted, so it is
+// stable between calls.
+func (metrics *PrometheusMetrics) WriteTo(w io.Writer) (int64, error) {
+	metrics.mutex.Lock()
+	defer metrics.mutex.Unlock()
+	var builder strings.Builder
+
+	builder.WriteString("# HELP deepl_requests_total Total number of requests sent to the DeepL API.\n")
+	builder.WriteString("# TYPE deepl_requests_total counter\n")
+	for _, key := range sortedPairs(metrics.requests) {
+		fmt.Fprintf(&builder, "deepl_requests_total{endpoint=%s,status=%s} %d\n", quoteLabel(key[0]),
+			quoteLabel(key[1]), metrics.requests[key])
+	}
+
+	builder.WriteString("# HELP deepl_request_duration_seconds Latency of requests sent to the DeepL API.\n")
+	builder.WriteString("# TYPE deepl_request_duration_seconds histogram\n")
+	endpoints := make([]string, 0, len(metrics.latencies))
+	for endpoint := range metrics.latencies {
+		endpoints = append(endpoints, endpoint)
+	}
+	sort.Strings(endpoints)
+	for _, endpoint := range endpoints {
+		latency := metrics.latencies[endpoint]
+		for i, bound := range metrics.buckets {
+			fmt.Fprintf(&builder, "deepl_request_duration_seconds_bucket{endpoint=%s,le=%s} %d\n",
+				quoteLabel(endpoint), quoteLabel(formatFloat(bound)), latency.counts[i])
+		}
+		fmt.Fprintf(&builder, "deepl_request_duration_seconds_bucket{endpoint=%s,le=\"+Inf\"} %d\n",
+			quoteLabel(endpoint), latency.count)
+		fmt.Fprintf(&builder, "deepl_request_duration_seconds_sum{endpoint=%s} %s\n", quoteLabel(endpoint),
+			formatFloat(latency.sum))
+		fmt.Fprintf(&builder, "deepl_request_duration_seconds_count{endpoint=%s} %d\n", quoteLabel(endpoint),
+			latency.count)
+	}
+
+	builder.WriteString("# HELP deepl_characters_total Total number of characters translated by the DeepL API.\n")
+	builder.WriteString("# TYPE deepl_characters_total counter\n")
+	for _, key := range sortedPairs(metrics.characters) {
+		fmt.Fprintf(&builder, "deepl_characters_total{source_lang=%s,target_lang=%s} %d\n", quoteLabel(key[0]),
+			quoteLabel(key[1]), metrics.characters[key])
+	}
+
+	if metrics.usage != nil {
+		builder.WriteString("# HELP deepl_usage_character_count Characters translated in the current billing period.\n")
+		builder.WriteString("# TYPE deepl_usage_character_count gauge\n")
+		fmt.Fprintf(&builder, "deepl_usage_character_count %d\n", metrics.usage.CharacterCount)
+		if metrics.usage.HasCharacterLimit() {
+			builder.WriteString("# HELP deepl_usage_character_limit Character limit of the current billing period.\n")
+			builder.WriteString("# TYPE deepl_usage_character_limit gauge\n")
+			fmt.Fprintf(&builder, "deepl_usage_character_limit %d\n", metrics.usage.CharacterLimit)
+		}
+		if metrics.usage.HasDocumentLimit() {
+			builder.WriteString("# HELP deepl_usage_document_count Documents translated in the current billing period.\n")
+			builder.WriteString("# TYPE deepl_usage_document_count gauge\n")
+			fmt.Fprintf(&builder, "deepl_usage_document_count %d\n", metrics.usage.DocumentCount)
+			builder.WriteString("# HELP deepl_usage_document_limit Document limit of the current billing period.\n")
+			builder.WriteString("# TYPE deepl_usage_document_limit gauge\n")
+			fmt.Fprintf(&builder, "deepl_usage_document_limit %d\n", metrics.usage.DocumentLimit)
+		}
+	}
+
+	written, err := io.WriteString(w, builder.String())
+	return int64(written), err
+}
+
+// sortedPairs returns the keys of the map in sorted order.
+func sortedPairs(values map[[2]string]uint64) [][2]string {
+	keys := make([][2]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i][0] != keys[j][0] {
+			return keys[i][0] < keys[j][0]
+		}
+		return keys[i][1] < keys[j][1]
+	})
+	return keys
+}
+
+// labelEscaper escapes label values according to the Prometheus text exposition format.
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// quoteLabel returns the escaped and quoted label value.
+func quoteLabel(value string) string {
+	return `"` + labelEscaper.Replace(value) + `"`
+}
+
+// formatFloat formats the float as expected by Prometheus.
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
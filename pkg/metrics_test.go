@@ -0,0 +1,83 @@
+package deeplclient
+
+import (
+	"github.com/PineiroHosting/deeplgobindings/pkg/deepltest"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestPrometheusMetrics tests whether requests, characters and the usage are exposed by the metrics handler.
+func TestPrometheusMetrics(t *testing.T) {
+	server := deepltest.NewServer()
+	defer server.Close()
+	server.InjectError(deepltest.ErrorInjection{Path: "translate", StatusCode: http.StatusServiceUnavailable, Count: 1})
+	metrics := NewPrometheusMetrics()
+	client, err := NewClient("test", WithEndpoint(server.URL), WithMetrics(metrics),
+		WithRetryPolicy(&RetryPolicy{MaxAttempts: 2}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = client.Translate(&TranslationRequest{Text: "Hallo Welt!", TargetLang: LangENGB}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = client.TranslateBatch(&TranslationRequest{SourceLang: LangFR, TargetLang: LangDE},
+		[]string{"Bonjour", "Salut"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = client.GetUsage(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = client.CheckDocumentTranslationStatus(&DocumentTranslationStatusRequest{
+		DocumentId:  "unknown",
+		DocumentKey: "unknown",
+	}); err == nil {
+		t.Fatal("expected error for unknown document")
+	}
+
+	scrapeServer := httptest.NewServer(metrics)
+	defer scrapeServer.Close()
+	resp, err := http.Get(scrapeServer.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exposition := string(body)
+	for _, expected := range []string{
+		`deepl_requests_total{endpoint="translate",status="200"} 2`,
+		`deepl_requests_total{endpoint="translate",status="503"} 1`,
+		`deepl_requests_total{endpoint="usage",status="200"} 1`,
+		`deepl_requests_total{endpoint="document/:id",status="404"} 1`,
+		`deepl_request_duration_seconds_bucket{endpoint="translate",le="+Inf"} 3`,
+		`deepl_request_duration_seconds_count{endpoint="usage"} 1`,
+		`deepl_characters_total{source_lang="EN",target_lang="EN-GB"} 11`,
+		`deepl_characters_total{source_lang="FR",target_lang="DE"} 12`,
+		"deepl_usage_character_count 23",
+		"deepl_usage_character_limit 500000",
+	} {
+		if !strings.Contains(exposition, expected) {
+			t.Errorf("metric %s is missing in exposition:\n%s", expected, exposition)
+		}
+	}
+}
+
+// TestEndpointLabel tests whether IDs are removed from the endpoints.
+func TestEndpointLabel(t *testing.T) {
+	tests := map[string]string{
+		"translate":                  "translate",
+		"document/0123/result":       "document/:id/result",
+		"glossaries/abc-def/entries": "glossaries/:id/entries",
+		"glossaries/abc-def":         "glossaries/:id",
+	}
+	for uri, expected := range tests {
+		if label := endpointLabel(uri); label != expected {
+			t.Errorf("unexpected label for %s: %s", uri, label)
+		}
+	}
+}
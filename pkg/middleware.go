@@ -0,0 +1,48 @@
+package deeplclient
+
+import (
+	"errors"
+	"net/http"
+)
+
+// RoundTripFunc sends a single HTTP request to the API server and returns its response.
+type RoundTripFunc func(req *http.Request) (*http.Response, error)
+
+// Middleware wraps the sending of requests, so requests can be observed or altered (e.g. to add request IDs or sign
+// headers) and responses can be inspected or replaced (e.g. to inject faults in tests). A middleware has to call next
+// in order to send the request, unless it responds on its own.
+type Middleware func(next RoundTripFunc) RoundTripFunc
+
+// WithMiddleware adds the given middlewares to the client. The first middleware is the outermost one, so it sees the
+// request first and the response last. Middlewares are called for every attempt of a request, including retries.
+func WithMiddleware(middlewares ...Middleware) Option {
+	return func(client *Client) error {
+		for _, middleware := range middlewares {
+			if middleware == nil {
+				return errors.New("middleware cannot be nil")
+			}
+		}
+		client.middlewares = append(append([]Middleware(nil), client.middlewares...), middlewares...)
+		return nil
+	}
+}
+
+// roundTrip sends the request through all middlewares of the client and finally via its HTTP client.
+func (client *Client) roundTrip(req *http.Request) (*http.Response, error) {
+	next := RoundTripFunc(client.Do)
+	for i := len(client.middlewares) - 1; i >= 0; i-- {
+		next = client.middlewares[i](next)
+	}
+	resp, err := next(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp == nil {
+		return nil, errors.New("middleware returned neither a response nor an error")
+	}
+	// responses created by middlewares may omit the body
+	if resp.Body == nil {
+		resp.Body = http.NoBody
+	}
+	return resp, nil
+}
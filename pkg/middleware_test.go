@@ -0,0 +1,62 @@
+package deeplclient
+
+import (
+	"github.com/PineiroHosting/deeplgobindings/pkg/deepltest"
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+// TestMiddleware tests whether middlewares are called in order for every attempt and can alter requests and responses.
+func TestMiddleware(t *testing.T) {
+	server := deepltest.NewServer()
+	defer server.Close()
+	var calls []string
+	requestId := 0
+	requestIds := func(next RoundTripFunc) RoundTripFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			calls = append(calls, "request id")
+			requestId++
+			req.Header.Set("X-Request-Id", strconv.Itoa(requestId))
+			return next(req)
+		}
+	}
+	faults := 1
+	faultInjection := func(next RoundTripFunc) RoundTripFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			calls = append(calls, "fault injection")
+			if faults > 0 {
+				faults--
+				return &http.Response{StatusCode: http.StatusServiceUnavailable, Header: http.Header{}, Request: req}, nil
+			}
+			return next(req)
+		}
+	}
+	client, err := NewClient("test",
+		WithEndpoint(server.URL),
+		WithRetryPolicy(&RetryPolicy{MaxAttempts: 2}),
+		WithMiddleware(requestIds, faultInjection),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = client.GetUsage(); err != nil {
+		t.Fatal(err)
+	}
+	expectedCalls := []string{"request id", "fault injection", "request id", "fault injection"}
+	if len(calls) != len(expectedCalls) {
+		t.Fatalf("unexpected middleware calls: %v", calls)
+	}
+	for i := range calls {
+		if calls[i] != expectedCalls[i] {
+			t.Fatalf("unexpected middleware calls: %v", calls)
+		}
+	}
+	requests := server.Requests()
+	if len(requests) != 1 || requests[0].Header.Get("X-Request-Id") != "2" {
+		t.Fatalf("unexpected requests received by the server: %+v", requests)
+	}
+	if _, err = NewClient("test", WithMiddleware(nil)); err == nil {
+		t.Fatal("expected error for nil middleware")
+	}
+}
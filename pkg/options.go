@@ -0,0 +1,230 @@
+package deeplclient
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// ProEndpointUrl is the endpoint of the DeepL API for auth keys of paid plans.
+	ProEndpointUrl = "https://api.deepl.com/v2/"
+	// FreeEndpointUrl is the endpoint of the DeepL API for auth keys of the free plan.
+	FreeEndpointUrl = "https://api-free.deepl.com/v2/"
+	// freeAuthKeySuffix is the suffix of all auth keys of the free plan
+	freeAuthKeySuffix = ":fx"
+	// apiVersionPath is the path of the supported API version
+	apiVersionPath = "/v2"
+)
+
+// Option configures a client created by NewClient. Options return an error if their values are invalid.
+type Option func(client *Client) error
+
+// attachment binds a component, which can only be used by a single client, to the client. Attachments are applied by
+// NewClient after all options succeeded, so components are never bound to clients whose creation failed.
+type attachment struct {
+	// attach binds the component to the client and fails if it is already bound to another one
+	attach func() error
+	// detach reverts attach if the creation of the client fails because of a later attachment
+	detach func()
+}
+
+// IsFreeAuthKey returns whether the given auth key belongs to the free plan and therefore has to be used with the
+// free API endpoint.
+func IsFreeAuthKey(authKey string) bool {
+	return strings.HasSuffix(authKey, freeAuthKeySuffix)
+}
+
+// NewClient creates a new client for the given auth key. The endpoint is chosen automatically depending on the type of
+// the auth key (see IsFreeAuthKey) unless another endpoint is set by an option. The HTTP client uses timeouts for
+// establishing connections and awaiting responses, while the transfer of large documents is not limited.
+//
+// All options are validated before the client is returned, so misconfiguration is reported here instead of on the
+// first request. The returned client is safe for concurrent use by multiple goroutines.
+func NewClient(authKey string, options ...Option) (*Client, error) {
+	if authKey == "" {
+		return nil, errors.New("auth key cannot be empty")
+	}
+	client := &Client{
+		Client:      newDefaultHTTPClient(),
+		AuthKey:     []byte(authKey),
+		EndpointUrl: ProEndpointUrl,
+	}
+	if IsFreeAuthKey(authKey) {
+		client.EndpointUrl = FreeEndpointUrl
+	}
+	for _, option := range options {
+		if err := option(client); err != nil {
+			return nil, err
+		}
+	}
+	for i, pending := range client.attachments {
+		if err := pending.attach(); err != nil {
+			for _, attached := range client.attachments[:i] {
+				attached.detach()
+			}
+			return nil, err
+		}
+	}
+	client.attachments = nil
+	return client, nil
+}
+
+// WithEndpoint sets the endpoint of the API server, e.g. to use a proxy or a fake server. The URL is normalized, so the
+// version path and trailing slash may be omitted.
+func WithEndpoint(endpointUrl string) Option {
+	return func(client *Client) (err error) {
+		client.EndpointUrl, err = NormalizeEndpointUrl(endpointUrl)
+		return
+	}
+}
+
+// WithHTTPClient sets the HTTP client used to send the requests instead of the default one created by NewClient.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(client *Client) error {
+		if httpClient == nil {
+			return errors.New("HTTP client cannot be nil")
+		}
+		client.Client = httpClient
+		return nil
+	}
+}
+
+// WithUserAgent sets the User-Agent header sent with every request.
+func WithUserAgent(userAgent string) Option {
+	return func(client *Client) error {
+		if userAgent == "" || strings.ContainsAny(userAgent, "\r\n") {
+			return fmt.Errorf("invalid user agent %q", userAgent)
+		}
+		client.userAgent = userAgent
+		return nil
+	}
+}
+
+// WithDefaultHeaders adds the given headers to every request. The headers required by the API (Authorization and
+// Content-Type) cannot be overwritten. Calling the option several times merges the headers.
+func WithDefaultHeaders(header http.Header) Option {
+	return func(client *Client) error {
+		for name, values := range header {
+			if name == "" || strings.ContainsAny(name, " \t\r\n:") {
+				return fmt.Errorf("invalid header name %q", name)
+			}
+			for _, value := range values {
+				if strings.ContainsAny(value, "\r\n") {
+					return fmt.Errorf("invalid value of header %s", name)
+				}
+			}
+			if client.header == nil {
+				client.header = http.Header{}
+			}
+			// values are copied, so later modifications of the given header do not affect the client
+			for _, value := range values {
+				client.header.Add(name, value)
+			}
+		}
+		return nil
+	}
+}
+
+// WithRetryPolicy sets the policy for retrying requests which failed with a temporary error. By default, every request
+// is sent only once.
+func WithRetryPolicy(policy *RetryPolicy) Option {
+	return func(client *Client) error {
+		if policy == nil {
+			return errors.New("retry policy cannot be nil")
+		}
+		if err := policy.validate(); err != nil {
+			return err
+		}
+		// the policy is copied, so later modifications do not affect the client
+		copied := *policy
+		client.retryPolicy = &copied
+		return nil
+	}
+}
+
+// WithRateLimit sets the client-side rate limiter, which limits the amount of requests and characters sent to the API
+// server. The limiter may be shared by several clients using the same auth key. By default, requests are not limited
+// on the client side.
+func WithRateLimit(limiter *RateLimiter) Option {
+	return func(client *Client) error {
+		if limiter == nil {
+			return errors.New("rate limiter cannot be nil")
+		}
+		client.rateLimiter = limiter
+		return nil
+	}
+}
+
+// WithBatchConcurrency sets the maximum amount of requests sent concurrently by a batch translation. Defaults to 4.
+func WithBatchConcurrency(concurrency int) Option {
+	return func(client *Client) error {
+		if concurrency < 1 {
+			return fmt.Errorf("batch concurrency must be at least 1, got %d", concurrency)
+		}
+		client.batchConcurrency = concurrency
+		return nil
+	}
+}
+
+// WithSplitOversizedTexts enables splitting of texts which exceed the maximum body size of a translation request.
+// Such texts are split on paragraph and sentence boundaries, translated piece by piece and joined again.
+func WithSplitOversizedTexts() Option {
+	return func(client *Client) error {
+		client.splitOversizedTexts = true
+		return nil
+	}
+}
+
+// WithLogger sets the logger receiving debug messages about the requests, e.g. a *slog.Logger.
+func WithLogger(logger Logger) Option {
+	return func(client *Client) error {
+		if logger == nil {
+			return errors.New("logger cannot be nil")
+		}
+		client.logger = logger
+		return nil
+	}
+}
+
+// NormalizeEndpointUrl validates the given endpoint URL and appends the version path and trailing slash if missing,
+// so "https://api.deepl.com" becomes "https://api.deepl.com/v2/".
+func NormalizeEndpointUrl(endpointUrl string) (string, error) {
+	parsed, err := url.Parse(endpointUrl)
+	if err != nil {
+		return "", fmt.Errorf("invalid endpoint url: %w", err)
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return "", fmt.Errorf("invalid endpoint url %q: scheme must be http or https", endpointUrl)
+	}
+	if parsed.Host == "" {
+		return "", fmt.Errorf("invalid endpoint url %q: host is missing", endpointUrl)
+	}
+	if parsed.RawQuery != "" || parsed.Fragment != "" {
+		return "", fmt.Errorf("invalid endpoint url %q: query and fragment are not allowed", endpointUrl)
+	}
+	path := strings.TrimRight(parsed.Path, "/")
+	if !strings.HasSuffix(path, apiVersionPath) {
+		path += apiVersionPath
+	}
+	parsed.Path = path + "/"
+	parsed.RawPath = ""
+	return parsed.String(), nil
+}
+
+// newDefaultHTTPClient creates the HTTP client used by NewClient. Instead of a timeout for the whole request, which
+// would abort the transfer of large documents, the single phases of a request are limited.
+func newDefaultHTTPClient() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{
+		Timeout:   10 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}).DialContext
+	transport.TLSHandshakeTimeout = 10 * time.Second
+	transport.ResponseHeaderTimeout = 60 * time.Second
+	return &http.Client{Transport: transport}
+}
@@ -0,0 +1,188 @@
+package deeplclient
+
+import (
+	"fmt"
+	"github.com/PineiroHosting/deeplgobindings/pkg/deepltest"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// TestNewClientEndpoint tests whether the endpoint is chosen depending on the type of the auth key.
+func TestNewClientEndpoint(t *testing.T) {
+	client, err := NewClient("00000000-0000-0000-0000-000000000000:fx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if client.EndpointUrl != FreeEndpointUrl {
+		t.Fatalf("unexpected endpoint for free auth key: %s", client.EndpointUrl)
+	}
+	if client, err = NewClient("00000000-0000-0000-0000-000000000000"); err != nil {
+		t.Fatal(err)
+	}
+	if client.EndpointUrl != ProEndpointUrl {
+		t.Fatalf("unexpected endpoint for pro auth key: %s", client.EndpointUrl)
+	}
+	if client.Client == nil {
+		t.Fatal("expected default HTTP client")
+	}
+	if _, err = NewClient(""); err == nil {
+		t.Fatal("expected error for empty auth key")
+	}
+}
+
+// TestNormalizeEndpointUrl tests the validation and normalization of endpoint URLs.
+func TestNormalizeEndpointUrl(t *testing.T) {
+	tests := []struct {
+		endpointUrl string
+		expected    string
+	}{
+		{"https://api.deepl.com", "https://api.deepl.com/v2/"},
+		{"https://api.deepl.com/", "https://api.deepl.com/v2/"},
+		{"https://api.deepl.com/v2", "https://api.deepl.com/v2/"},
+		{"https://api.deepl.com/v2/", "https://api.deepl.com/v2/"},
+		{"http://localhost:8080/proxy", "http://localhost:8080/proxy/v2/"},
+		{"api.deepl.com", ""},
+		{"ftp://api.deepl.com", ""},
+		{"https:///v2", ""},
+		{"https://api.deepl.com/v2?auth_key=secret", ""},
+	}
+	for _, test := range tests {
+		normalized, err := NormalizeEndpointUrl(test.endpointUrl)
+		if test.expected == "" {
+			if err == nil {
+				t.Errorf("expected error for %q, got: %s", test.endpointUrl, normalized)
+			}
+		} else if err != nil || normalized != test.expected {
+			t.Errorf("unexpected result for %q: %s, %v", test.endpointUrl, normalized, err)
+		}
+	}
+}
+
+// TestNewClientWithEndpoint tests whether a client created with a custom endpoint can access the API server.
+func TestNewClientWithEndpoint(t *testing.T) {
+	server := deepltest.NewServer()
+	defer server.Close()
+	client, err := NewClient("test", WithEndpoint(server.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = client.GetUsage(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = NewClient("test", WithEndpoint("invalid")); err == nil {
+		t.Fatal("expected error for invalid endpoint")
+	}
+}
+
+// testLogger collects the messages of a client.
+type testLogger struct {
+	mutex    sync.Mutex
+	messages []string
+}
+
+// Debug collects the message including its arguments.
+func (logger *testLogger) Debug(msg string, args ...interface{}) {
+	logger.mutex.Lock()
+	defer logger.mutex.Unlock()
+	logger.messages = append(logger.messages, strings.TrimSpace(fmt.Sprintln(append([]interface{}{msg}, args...)...)))
+}
+
+// count returns the amount of collected messages starting with the given prefix.
+func (logger *testLogger) count(prefix string) (count int) {
+	logger.mutex.Lock()
+	defer logger.mutex.Unlock()
+	for _, message := range logger.messages {
+		if strings.HasPrefix(message, prefix) {
+			count++
+		}
+	}
+	return
+}
+
+// contains returns whether any collected message contains the given text.
+func (logger *testLogger) contains(text string) bool {
+	logger.mutex.Lock()
+	defer logger.mutex.Unlock()
+	for _, message := range logger.messages {
+		if strings.Contains(message, text) {
+			return true
+		}
+	}
+	return false
+}
+
+// TestNewClientOptions tests whether the options are applied to the requests of the client.
+func TestNewClientOptions(t *testing.T) {
+	server := deepltest.NewServer()
+	defer server.Close()
+	server.InjectError(deepltest.ErrorInjection{Path: "usage", StatusCode: http.StatusServiceUnavailable, Count: 1})
+	header := http.Header{}
+	header.Set("X-Request-Source", "test")
+	header.Set("Authorization", "overwritten")
+	logger := &testLogger{}
+	client, err := NewClient("test",
+		WithEndpoint(server.URL),
+		WithHTTPClient(server.Client()),
+		WithUserAgent("deeplgobindings-test/1.0"),
+		WithDefaultHeaders(header),
+		WithRetryPolicy(&RetryPolicy{MaxAttempts: 2}),
+		WithRateLimit(NewRateLimiter(100, 0, 0)),
+		WithBatchConcurrency(2),
+		WithSplitOversizedTexts(),
+		WithLogger(logger),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if client.RetryPolicy().MaxAttempts != 2 || client.RateLimiter() == nil || client.BatchConcurrency() != 2 ||
+		!client.SplitOversizedTexts() {
+		t.Fatal("unexpected configuration of client")
+	}
+	// the returned policy is a copy, so modifying it must not affect the client
+	client.RetryPolicy().MaxAttempts = 5
+	if client.RetryPolicy().MaxAttempts != 2 {
+		t.Fatal("retry policy of client has been modified")
+	}
+	// modifying the given header must not affect the client
+	header.Set("X-Request-Source", "modified")
+	if _, err = client.GetUsage(); err != nil {
+		t.Fatal(err)
+	}
+	requests := server.Requests()
+	if len(requests) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(requests))
+	}
+	for _, req := range requests {
+		if req.Header.Get("User-Agent") != "deeplgobindings-test/1.0" || req.Header.Get("X-Request-Source") != "test" ||
+			req.Header.Get("Authorization") != "DeepL-Auth-Key test" {
+			t.Fatalf("unexpected request header: %v", req.Header)
+		}
+	}
+	if logger.count("retrying deepl api request") != 1 {
+		t.Fatalf("expected one debug message about the retry, got: %v", logger.messages)
+	}
+}
+
+// TestNewClientInvalidOptions tests whether invalid options are rejected when the client is created.
+func TestNewClientInvalidOptions(t *testing.T) {
+	options := []Option{
+		WithHTTPClient(nil),
+		WithUserAgent(""),
+		WithUserAgent("agent\r\nX-Injected: true"),
+		WithDefaultHeaders(http.Header{"Invalid Name": {"value"}}),
+		WithDefaultHeaders(http.Header{"X-Valid": {"invalid\nvalue"}}),
+		WithRetryPolicy(nil),
+		WithRetryPolicy(&RetryPolicy{MaxAttempts: -1}),
+		WithRetryPolicy(&RetryPolicy{Jitter: 2}),
+		WithRateLimit(nil),
+		WithBatchConcurrency(0),
+		WithLogger(nil),
+	}
+	for i, option := range options {
+		if _, err := NewClient("test", option); err == nil {
+			t.Errorf("expected error for option %d", i)
+		}
+	}
+}
@@ -0,0 +1,298 @@
+package deeplclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultQuotaRefreshInterval is the default time after which the usage is retrieved again by a quota guard
+	defaultQuotaRefreshInterval = 5 * time.Minute
+)
+
+// BudgetExceededErr indicates that a request has been rejected on the client side, because it would exceed the
+// configured character budget. It matches ErrQuotaExceeded using errors.Is.
+type BudgetExceededErr struct {
+	// Budget is the amount of characters which may be translated in the current billing period.
+	Budget int64
+	// Used is the amount of characters used so far (including characters sent since the last usage refresh).
+	Used int64
+	// Requested is the amount of characters of the rejected request.
+	Requested int64
+}
+
+// Error returns a compact version of all error information in order to implement the error interface.
+func (err *BudgetExceededErr) Error() string {
+	return fmt.Sprintf("character budget exceeded: %d of %d characters used, %d requested", err.Used, err.Budget,
+		err.Requested)
+}
+
+// Is reports whether the target is ErrQuotaExceeded, so budget errors are handled like exceeded quotas.
+func (err *BudgetExceededErr) Is(target error) bool {
+	return target == ErrQuotaExceeded
+}
+
+// QuotaGuardOptions configures a QuotaGuard.
+type QuotaGuardOptions struct {
+	// Budget is the soft limit of characters which may be translated in the current billing period. If zero,
+	// BudgetPercent is used.
+	Budget int64
+	// BudgetPercent is the soft limit of characters as percentage (between 0 and 100) of the character limit of the
+	// account. If both Budget and BudgetPercent are zero, the character limit of the account is used.
+	BudgetPercent float64
+	// RefreshInterval is the time after which the usage is retrieved from the API server again. Defaults to five
+	// minutes.
+	RefreshInterval time.Duration
+	// Block lets requests exceeding the budget wait until the budget allows them (e.g. after a new billing period has
+	// started) instead of failing with a *BudgetExceededErr. The usage is refreshed once per refresh interval while
+	// waiting. Requests with more characters than the whole budget still fail immediately.
+	Block bool
+	// Thresholds are fractions of the budget (e.g. 0.8 and 0.95) which trigger OnThreshold when they are crossed.
+	Thresholds []float64
+	// OnThreshold is called with the crossed threshold and the current usage whenever the used characters cross one
+	// of the thresholds. It is called again if the usage drops below the threshold and crosses it later on.
+	OnThreshold func(threshold float64, used, budget int64)
+}
+
+// QuotaGuard rejects or blocks requests on the client side which would exceed a soft character budget, so a batch
+// does not fail with an exceeded quota halfway. The guard retrieves the usage periodically and tracks the characters
+// sent in between locally. As the documents are billed by the server after their translation, only their billed
+// characters are tracked and uploads are rejected once the budget is exhausted.
+//
+// The usage is retrieved before the first request, which fails if the usage cannot be retrieved. Characters of
+// requests which are still pending while the usage is refreshed may be counted twice or not at all until the next
+// refresh. A guard is attached to a client with WithQuotaGuard and is safe for concurrent use.
+type QuotaGuard struct {
+	options QuotaGuardOptions
+	// usage retrieves the current usage from the API server
+	usage func(ctx context.Context) (*UsageResponse, error)
+
+	mutex sync.Mutex
+	// refreshed is the time of the last usage refresh
+	refreshed time.Time
+	// serverUsed is the amount of characters reported by the last usage refresh
+	serverUsed int64
+	// localUsed is the amount of characters sent since the last usage refresh
+	localUsed int64
+	// budget is the current budget, which is negative if unlimited
+	budget int64
+	// crossed contains the thresholds which have been crossed
+	crossed map[float64]bool
+	// billedDocuments contains the documents whose billed characters have been tracked since the last refresh
+	billedDocuments map[string]bool
+}
+
+// NewQuotaGuard creates a new quota guard with the given options, which are validated.
+func NewQuotaGuard(options QuotaGuardOptions) (*QuotaGuard, error) {
+	switch {
+	case options.Budget < 0:
+		return nil, errors.New("budget of quota guard cannot be negative")
+	case options.BudgetPercent < 0 || options.BudgetPercent > 100:
+		return nil, errors.New("budget percentage of quota guard has to be between 0 and 100")
+	case options.RefreshInterval < 0:
+		return nil, errors.New("refresh interval of quota guard cannot be negative")
+	}
+	for _, threshold := range options.Thresholds {
+		if threshold <= 0 || threshold > 1 {
+			return nil, errors.New("thresholds of quota guard have to be between 0 and 1")
+		}
+	}
+	if options.RefreshInterval == 0 {
+		options.RefreshInterval = defaultQuotaRefreshInterval
+	}
+	options.Thresholds = append([]float64(nil), options.Thresholds...)
+	sort.Float64s(options.Thresholds)
+	return &QuotaGuard{
+		options:         options,
+		crossed:         map[float64]bool{},
+		billedDocuments: map[string]bool{},
+	}, nil
+}
+
+// WithQuotaGuard attaches the quota guard to the client, so translations and document uploads are checked against
+// the budget before they are sent. A guard can only be attached to a single client.
+func WithQuotaGuard(guard *QuotaGuard) Option {
+	return func(client *Client) error {
+		if guard == nil {
+			return errors.New("quota guard cannot be nil")
+		}
+		client.quota = guard
+		client.attachments = append(client.attachments, attachment{
+			attach: func() error {
+				return guard.attach(client.GetUsageWithContext)
+			},
+			detach: guard.detach,
+		})
+		return nil
+	}
+}
+
+// attach binds the guard to the client whose usage is retrieved by the given function.
+func (guard *QuotaGuard) attach(usage func(ctx context.Context) (*UsageResponse, error)) error {
+	guard.mutex.Lock()
+	defer guard.mutex.Unlock()
+	if guard.usage != nil {
+		return errors.New("quota guard is already attached to a client")
+	}
+	guard.usage = usage
+	return nil
+}
+
+// detach releases the guard from the client it has been attached to.
+func (guard *QuotaGuard) detach() {
+	guard.mutex.Lock()
+	defer guard.mutex.Unlock()
+	guard.usage = nil
+}
+
+// Refresh retrieves the current usage from the API server and resets the locally tracked characters.
+func (guard *QuotaGuard) Refresh(ctx context.Context) error {
+	guard.mutex.Lock()
+	usage := guard.usage
+	guard.mutex.Unlock()
+	if usage == nil {
+		return errors.New("quota guard is not attached to a client")
+	}
+	resp, err := usage(ctx)
+	if err != nil {
+		return err
+	}
+	guard.mutex.Lock()
+	guard.refreshed = time.Now()
+	guard.serverUsed = resp.CharacterCount
+	guard.localUsed = 0
+	guard.budget = guard.budgetOf(resp)
+	guard.billedDocuments = map[string]bool{}
+	notify := guard.crossedThresholds()
+	guard.mutex.Unlock()
+	notify()
+	return nil
+}
+
+// Usage returns the amount of characters used so far (including the characters sent since the last refresh) and the
+// budget, which is negative if unlimited. The usage is zero until the first refresh.
+func (guard *QuotaGuard) Usage() (used, budget int64) {
+	guard.mutex.Lock()
+	defer guard.mutex.Unlock()
+	return guard.serverUsed + guard.localUsed, guard.budget
+}
+
+// budgetOf returns the budget for the given usage, which is negative if unlimited.
+func (guard *QuotaGuard) budgetOf(resp *UsageResponse) int64 {
+	switch {
+	case guard.options.Budget > 0:
+		return guard.options.Budget
+	case !resp.HasCharacterLimit():
+		return -1
+	case guard.options.BudgetPercent > 0:
+		return int64(float64(resp.CharacterLimit) * guard.options.BudgetPercent / 100)
+	default:
+		return resp.CharacterLimit
+	}
+}
+
+// reserve checks whether the given amount of characters can be sent without exceeding the budget and tracks them.
+// Depending on the options, it either fails with a *BudgetExceededErr or waits until the characters fit into the
+// budget, unless the characters exceed the whole budget, which fails immediately. A nil guard never rejects any
+// request.
+func (guard *QuotaGuard) reserve(ctx context.Context, characters int64) error {
+	if guard == nil {
+		return nil
+	}
+	for {
+		if guard.refreshDue() {
+			if err := guard.Refresh(ctx); err != nil {
+				return err
+			}
+		}
+		guard.mutex.Lock()
+		used := guard.serverUsed + guard.localUsed
+		if guard.budget < 0 || !exceedsBudget(used, characters, guard.budget) {
+			guard.localUsed += characters
+			notify := guard.crossedThresholds()
+			guard.mutex.Unlock()
+			notify()
+			return nil
+		}
+		budgetErr := &BudgetExceededErr{Budget: guard.budget, Used: used, Requested: characters}
+		guard.mutex.Unlock()
+		// requests larger than the whole budget would wait forever
+		if !guard.options.Block || characters > budgetErr.Budget {
+			return budgetErr
+		}
+		if err := sleepContext(ctx, guard.options.RefreshInterval); err != nil {
+			return err
+		}
+		// the usage is refreshed on the next iteration
+		guard.mutex.Lock()
+		guard.refreshed = time.Time{}
+		guard.mutex.Unlock()
+	}
+}
+
+// exceedsBudget returns whether a request with the given amount of characters exceeds the budget, of which the given
+// amount has already been used. Requests without characters (e.g. document uploads, whose characters are billed after
+// their translation) only exceed an exhausted budget.
+func exceedsBudget(used, characters, budget int64) bool {
+	return used+characters > budget || characters == 0 && used >= budget
+}
+
+// release gives back characters which have been reserved for a failed request.
+func (guard *QuotaGuard) release(characters int64) {
+	if guard == nil {
+		return
+	}
+	guard.mutex.Lock()
+	defer guard.mutex.Unlock()
+	guard.localUsed -= characters
+}
+
+// recordDocument tracks the characters billed for the translated document once.
+func (guard *QuotaGuard) recordDocument(status *DocumentTranslationStatusResponse) {
+	if guard == nil || status.Status != StatusDone {
+		return
+	}
+	guard.mutex.Lock()
+	if guard.billedDocuments[status.DocumentId] {
+		guard.mutex.Unlock()
+		return
+	}
+	guard.billedDocuments[status.DocumentId] = true
+	guard.localUsed += int64(status.BilledCharacters)
+	notify := guard.crossedThresholds()
+	guard.mutex.Unlock()
+	notify()
+}
+
+// refreshDue returns whether the usage has to be retrieved again.
+func (guard *QuotaGuard) refreshDue() bool {
+	guard.mutex.Lock()
+	defer guard.mutex.Unlock()
+	return time.Since(guard.refreshed) >= guard.options.RefreshInterval
+}
+
+// crossedThresholds updates the crossed thresholds and returns a function which notifies about newly crossed ones.
+// The returned function has to be called after releasing the mutex, which has to be held by the caller.
+func (guard *QuotaGuard) crossedThresholds() func() {
+	if guard.options.OnThreshold == nil || guard.budget <= 0 {
+		return func() {}
+	}
+	used, budget := guard.serverUsed+guard.localUsed, guard.budget
+	var thresholds []float64
+	for _, threshold := range guard.options.Thresholds {
+		reached := float64(used) >= threshold*float64(budget)
+		if reached && !guard.crossed[threshold] {
+			thresholds = append(thresholds, threshold)
+		}
+		guard.crossed[threshold] = reached
+	}
+	return func() {
+		for _, threshold := range thresholds {
+			guard.options.OnThreshold(threshold, used, budget)
+		}
+	}
+}
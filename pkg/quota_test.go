@@ -0,0 +1,168 @@
+package deeplclient
+
+import (
+	"context"
+	"errors"
+	"github.com/PineiroHosting/deeplgobindings/pkg/deepltest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newQuotaGuard creates a new quota guard with the given options, which have to be valid.
+func newQuotaGuard(t *testing.T, options QuotaGuardOptions) *QuotaGuard {
+	guard, err := NewQuotaGuard(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return guard
+}
+
+// TestQuotaGuardBudget tests whether translations exceeding the budget are rejected before they are sent.
+func TestQuotaGuardBudget(t *testing.T) {
+	guard := newQuotaGuard(t, QuotaGuardOptions{Budget: 100, RefreshInterval: time.Hour})
+	client, server := newFakeClient(t, WithQuotaGuard(guard))
+	server.SetUsage(90, 1000)
+	if _, err := client.Translate(&TranslationRequest{Text: "Hallo", TargetLang: LangENGB}); err != nil {
+		t.Fatal(err)
+	}
+	if used, budget := guard.Usage(); used != 95 || budget != 100 {
+		t.Fatalf("unexpected usage: %d of %d", used, budget)
+	}
+	server.ResetRequests()
+	_, err := client.Translate(&TranslationRequest{Text: "Hallo Welt!", TargetLang: LangENGB})
+	var budgetErr *BudgetExceededErr
+	if !errors.As(err, &budgetErr) || !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("expected budget exceeded error, got: %v", err)
+	}
+	if budgetErr.Used != 95 || budgetErr.Requested != 11 || budgetErr.Budget != 100 {
+		t.Fatalf("unexpected budget error: %+v", budgetErr)
+	}
+	if len(server.Requests()) != 0 {
+		t.Fatalf("expected no requests, got %d", len(server.Requests()))
+	}
+	// failed requests do not count
+	server.InjectError(deepltest.ErrorInjection{Path: "translate", StatusCode: 500, Count: 1})
+	if _, err = client.Translate(&TranslationRequest{Text: "Hi", TargetLang: LangENGB}); err == nil {
+		t.Fatal("expected injected error")
+	}
+	if used, _ := guard.Usage(); used != 95 {
+		t.Fatalf("unexpected usage after failed request: %d", used)
+	}
+}
+
+// TestQuotaGuardThresholds tests whether the percentage budget and threshold callbacks work.
+func TestQuotaGuardThresholds(t *testing.T) {
+	var crossed []float64
+	guard := newQuotaGuard(t, QuotaGuardOptions{
+		BudgetPercent:   50,
+		RefreshInterval: time.Hour,
+		Thresholds:      []float64{0.8, 0.5},
+		OnThreshold: func(threshold float64, used, budget int64) {
+			if budget != 50 {
+				t.Errorf("unexpected budget: %d", budget)
+			}
+			crossed = append(crossed, threshold)
+		},
+	})
+	client, server := newFakeClient(t, WithQuotaGuard(guard))
+	server.SetUsage(0, 100)
+	for _, text := range []string{strings.Repeat("a", 20), strings.Repeat("b", 10), strings.Repeat("c", 12)} {
+		if _, err := client.Translate(&TranslationRequest{Text: text, TargetLang: LangENGB}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(crossed) != 2 || crossed[0] != 0.5 || crossed[1] != 0.8 {
+		t.Fatalf("unexpected crossed thresholds: %v", crossed)
+	}
+	if _, err := client.Translate(&TranslationRequest{Text: strings.Repeat("d", 9), TargetLang: LangENGB}); err == nil {
+		t.Fatal("expected budget exceeded error")
+	}
+}
+
+// TestQuotaGuardDocuments tests whether billed characters of documents are tracked and uploads are rejected once the
+// budget is exhausted.
+func TestQuotaGuardDocuments(t *testing.T) {
+	guard := newQuotaGuard(t, QuotaGuardOptions{Budget: 12, RefreshInterval: time.Hour})
+	client, _ := newFakeClient(t, WithQuotaGuard(guard))
+	req := &DocumentTranslationStartRequest{
+		TargetLang: LangDE,
+		File:       []byte("Hello world!"),
+		Filename:   "hello.txt",
+	}
+	document, err := client.StartDocumentTranslate(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = waitForDocumentTranslation(context.Background(), client, document, fastPolling); err != nil {
+		t.Fatal(err)
+	}
+	// the status of a finished document is only tracked once
+	if _, err = client.CheckDocumentTranslationStatus((*DocumentTranslationStatusRequest)(document)); err != nil {
+		t.Fatal(err)
+	}
+	if used, _ := guard.Usage(); used != 12 {
+		t.Fatalf("unexpected usage: %d", used)
+	}
+	if _, err = client.StartDocumentTranslate(req); !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("expected budget exceeded error, got: %v", err)
+	}
+}
+
+// TestQuotaGuardBlock tests whether requests wait until the budget allows them.
+func TestQuotaGuardBlock(t *testing.T) {
+	guard := newQuotaGuard(t, QuotaGuardOptions{Block: true, RefreshInterval: 10 * time.Millisecond})
+	client, server := newFakeClient(t, WithQuotaGuard(guard))
+	server.SetUsage(100, 100)
+	go func() {
+		time.Sleep(30 * time.Millisecond)
+		// a new billing period has started
+		server.SetUsage(0, 100)
+	}()
+	if _, err := client.Translate(&TranslationRequest{Text: "Hallo", TargetLang: LangENGB}); err != nil {
+		t.Fatal(err)
+	}
+
+	server.SetUsage(100, 100)
+	if err := guard.Refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	if _, err := client.TranslateWithContext(ctx, &TranslationRequest{Text: "Hallo", TargetLang: LangENGB}); !errors.Is(
+		err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded error, got: %v", err)
+	}
+
+	// requests larger than the whole budget fail immediately instead of waiting forever
+	_, err := client.Translate(&TranslationRequest{Text: strings.Repeat("a", 101), TargetLang: LangENGB})
+	var budgetErr *BudgetExceededErr
+	if !errors.As(err, &budgetErr) || budgetErr.Requested != 101 {
+		t.Fatalf("expected budget exceeded error, got: %v", err)
+	}
+}
+
+// TestQuotaGuardInvalidOptions tests whether invalid options are rejected.
+func TestQuotaGuardInvalidOptions(t *testing.T) {
+	for i, options := range []QuotaGuardOptions{
+		{Budget: -1},
+		{BudgetPercent: 101},
+		{RefreshInterval: -time.Second},
+		{Thresholds: []float64{1.5}},
+	} {
+		if _, err := NewQuotaGuard(options); err == nil {
+			t.Errorf("expected error for options %d", i)
+		}
+	}
+	// the guard must not be attached to a client whose creation failed
+	guard := newQuotaGuard(t, QuotaGuardOptions{})
+	if _, err := NewClient("test", WithQuotaGuard(guard), WithLogger(nil)); err == nil {
+		t.Fatal("expected error for invalid logger")
+	}
+	if _, err := NewClient("test", WithQuotaGuard(guard)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewClient("test", WithQuotaGuard(guard)); err == nil {
+		t.Fatal("expected error for guard attached twice")
+	}
+}
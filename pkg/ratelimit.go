@@ -0,0 +1,295 @@
+package deeplclient
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// minimum fraction of the configured rate the limiter slows down to after the server reported too many requests
+	minRateFactor = 1.0 / 16
+	// default time the limiter needs to recover from the minimum rate to the configured rate
+	defaultRateRecoveryPeriod = time.Minute
+)
+
+// RateLimiter is a client-side token bucket limiter which implements DeepL API's limitation rules by capping the
+// amount of requests per second and the amount of characters sent per interval. A single limiter may be shared by all
+// goroutines using the same Client (or even several clients using the same auth key).
+//
+// Whenever the server answers with "too many requests", the limiter halves its rate and recovers slowly back to the
+// configured rate afterwards.
+type RateLimiter struct {
+	mutex sync.Mutex
+	// requestsPerSecond is the configured amount of requests per second (unlimited if zero)
+	requestsPerSecond float64
+	// charactersPerSecond is the configured amount of characters per second (unlimited if zero)
+	charactersPerSecond float64
+	// characterBurst is the maximum amount of characters which can be sent at once
+	characterBurst float64
+	// recoveryPeriod is the time needed to recover from the minimum to the configured rate
+	recoveryPeriod time.Duration
+
+	requestTokens   float64
+	characterTokens float64
+	// factor is the fraction of the configured rates which is currently applied
+	factor float64
+	last   time.Time
+}
+
+// NewRateLimiter creates a new rate limiter allowing requestsPerSecond requests per second and charactersPerInterval
+// characters per interval. Passing zero for requestsPerSecond or charactersPerInterval disables the corresponding
+// limit.
+func NewRateLimiter(requestsPerSecond float64, charactersPerInterval int64, interval time.Duration) *RateLimiter {
+	limiter := &RateLimiter{
+		requestsPerSecond: requestsPerSecond,
+		recoveryPeriod:    defaultRateRecoveryPeriod,
+		factor:            1,
+	}
+	if charactersPerInterval > 0 && interval > 0 {
+		limiter.characterBurst = float64(charactersPerInterval)
+		limiter.charactersPerSecond = float64(charactersPerInterval) / interval.Seconds()
+	}
+	limiter.requestTokens = limiter.requestBurst()
+	limiter.characterTokens = limiter.characterBurst
+	return limiter
+}
+
+// SetRecoveryPeriod sets the time the limiter needs to recover from its minimum rate back to the configured rate after
+// the server reported too many requests.
+func (limiter *RateLimiter) SetRecoveryPeriod(recoveryPeriod time.Duration) {
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+	limiter.recoveryPeriod = recoveryPeriod
+}
+
+// Wait blocks until a request sending the given amount of characters is allowed or the context is done. A nil
+// limiter never blocks.
+func (limiter *RateLimiter) Wait(ctx context.Context, characters int) error {
+	if limiter == nil {
+		return ctx.Err()
+	}
+	for {
+		delay, ok := limiter.reserve(characters)
+		if ok {
+			return nil
+		}
+		if err := sleepContext(ctx, delay); err != nil {
+			return err
+		}
+	}
+}
+
+// reserve takes the tokens required for a request if available. Otherwise, it returns the estimated delay until the
+// tokens are available.
+func (limiter *RateLimiter) reserve(characters int) (time.Duration, bool) {
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+	limiter.refill(time.Now())
+
+	needRequests := limiter.requestsPerSecond > 0 && limiter.requestTokens < 1
+	// requests exceeding the burst size would wait forever, so they are allowed as soon as the bucket is full and
+	// drive it into debt instead
+	needCharacters := limiter.charactersPerSecond > 0 && limiter.characterTokens < float64(characters) &&
+		limiter.characterTokens < limiter.characterBurst
+	if !needRequests && !needCharacters {
+		if limiter.requestsPerSecond > 0 {
+			limiter.requestTokens--
+		}
+		if limiter.charactersPerSecond > 0 {
+			limiter.characterTokens -= float64(characters)
+		}
+		return 0, true
+	}
+
+	var delay time.Duration
+	if needRequests {
+		delay = secondsToDuration((1 - limiter.requestTokens) / (limiter.requestsPerSecond * limiter.factor))
+	}
+	if needCharacters {
+		missing := float64(characters)
+		if missing > limiter.characterBurst {
+			missing = limiter.characterBurst
+		}
+		missing -= limiter.characterTokens
+		characterDelay := secondsToDuration(missing / (limiter.charactersPerSecond * limiter.factor))
+		if characterDelay > delay {
+			delay = characterDelay
+		}
+	}
+	return delay, false
+}
+
+// refill adds the tokens accumulated since the last refill and lets the rate recover. The caller must hold the mutex.
+func (limiter *RateLimiter) refill(now time.Time) {
+	if limiter.last.IsZero() {
+		limiter.last = now
+		return
+	}
+	elapsed := now.Sub(limiter.last).Seconds()
+	if elapsed <= 0 {
+		return
+	}
+	limiter.last = now
+	limiter.requestTokens += elapsed * limiter.requestsPerSecond * limiter.factor
+	if burst := limiter.requestBurst(); limiter.requestTokens > burst {
+		limiter.requestTokens = burst
+	}
+	limiter.characterTokens += elapsed * limiter.charactersPerSecond * limiter.factor
+	if limiter.characterTokens > limiter.characterBurst {
+		limiter.characterTokens = limiter.characterBurst
+	}
+	if limiter.factor < 1 {
+		if limiter.recoveryPeriod > 0 {
+			limiter.factor += elapsed / limiter.recoveryPeriod.Seconds() * (1 - minRateFactor)
+		}
+		if limiter.factor > 1 || limiter.recoveryPeriod <= 0 {
+			limiter.factor = 1
+		}
+	}
+}
+
+// requestBurst returns the maximum amount of requests which can be sent at once. The caller must hold the mutex.
+func (limiter *RateLimiter) requestBurst() float64 {
+	if limiter.requestsPerSecond < 1 {
+		return 1
+	}
+	return limiter.requestsPerSecond
+}
+
+// Throttle halves the current rate of the limiter. It is called automatically by the client whenever the server
+// answers with "too many requests".
+func (limiter *RateLimiter) Throttle() {
+	if limiter == nil {
+		return
+	}
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+	limiter.refill(time.Now())
+	limiter.factor /= 2
+	if limiter.factor < minRateFactor {
+		limiter.factor = minRateFactor
+	}
+	// drain the buckets, so no further requests are sent immediately
+	if limiter.requestTokens > 0 {
+		limiter.requestTokens = 0
+	}
+	if limiter.characterTokens > 0 {
+		limiter.characterTokens = 0
+	}
+}
+
+// secondsToDuration converts fractional seconds into a duration.
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// WithThrottling decorates the translator, so each call waits for the given rate limiter. Whenever the decorated
+// translator fails because of too many requests, the limiter is throttled. In contrast to the rate limiter of a client
+// (see WithRateLimit), which limits single HTTP requests, the decorator limits calls of any translator. See
+// WithTextThrottling, WithDocumentThrottling and WithUsageThrottling for translators implementing only a part of the
+// Translator interface.
+func WithThrottling(next Translator, limiter *RateLimiter) Translator {
+	return &translatorSet{
+		TextTranslator:     WithTextThrottling(next, limiter),
+		DocumentTranslator: WithDocumentThrottling(next, limiter),
+		UsageReporter:      WithUsageThrottling(next, limiter),
+	}
+}
+
+// limitCall waits for the rate limiter before a call sending the given amount of characters, executes the call and
+// throttles the rate limiter if the call failed because too many requests have been sent.
+func limitCall(ctx context.Context, limiter *RateLimiter, characters int, call func() error) error {
+	if err := limiter.Wait(ctx, characters); err != nil {
+		return err
+	}
+	err := call()
+	if errors.Is(err, ErrTooManyRequests) {
+		limiter.Throttle()
+	}
+	return err
+}
+
+// rateLimitedTextTranslator is a decorator which waits for a rate limiter before each text translation.
+type rateLimitedTextTranslator struct {
+	next    TextTranslator
+	limiter *RateLimiter
+}
+
+// WithTextThrottling decorates the text translator, so each translation waits for the given rate limiter.
+func WithTextThrottling(next TextTranslator, limiter *RateLimiter) TextTranslator {
+	return &rateLimitedTextTranslator{next: next, limiter: limiter}
+}
+
+// TranslateWithContext waits for the rate limiter and calls the decorated translator.
+func (translator *rateLimitedTextTranslator) TranslateWithContext(ctx context.Context, req *TranslationRequest) (
+	resp *TranslationResponse, err error) {
+	err = limitCall(ctx, translator.limiter, utf8.RuneCountInString(req.Text), func() (err error) {
+		resp, err = translator.next.TranslateWithContext(ctx, req)
+		return
+	})
+	return
+}
+
+// rateLimitedDocumentTranslator is a decorator which waits for a rate limiter before each document API call.
+type rateLimitedDocumentTranslator struct {
+	next    DocumentTranslator
+	limiter *RateLimiter
+}
+
+// WithDocumentThrottling decorates the document translator, so each call waits for the given rate limiter.
+func WithDocumentThrottling(next DocumentTranslator, limiter *RateLimiter) DocumentTranslator {
+	return &rateLimitedDocumentTranslator{next: next, limiter: limiter}
+}
+
+// StartDocumentTranslateWithContext waits for the rate limiter and calls the decorated translator.
+func (translator *rateLimitedDocumentTranslator) StartDocumentTranslateWithContext(ctx context.Context,
+	req *DocumentTranslationStartRequest) (resp *DocumentTranslationStartResponse, err error) {
+	err = limitCall(ctx, translator.limiter, 0, func() (err error) {
+		resp, err = translator.next.StartDocumentTranslateWithContext(ctx, req)
+		return
+	})
+	return
+}
+
+// CheckDocumentTranslationStatusWithContext waits for the rate limiter and calls the decorated translator.
+func (translator *rateLimitedDocumentTranslator) CheckDocumentTranslationStatusWithContext(ctx context.Context,
+	req *DocumentTranslationStatusRequest) (resp *DocumentTranslationStatusResponse, err error) {
+	err = limitCall(ctx, translator.limiter, 0, func() (err error) {
+		resp, err = translator.next.CheckDocumentTranslationStatusWithContext(ctx, req)
+		return
+	})
+	return
+}
+
+// DownloadTranslatedDocumentWithContext waits for the rate limiter and calls the decorated translator.
+func (translator *rateLimitedDocumentTranslator) DownloadTranslatedDocumentWithContext(ctx context.Context,
+	req *DocumentTranslationDownloadRequest) (result []byte, err error) {
+	err = limitCall(ctx, translator.limiter, 0, func() (err error) {
+		result, err = translator.next.DownloadTranslatedDocumentWithContext(ctx, req)
+		return
+	})
+	return
+}
+
+// rateLimitedUsageReporter is a decorator which waits for a rate limiter before each usage request.
+type rateLimitedUsageReporter struct {
+	next    UsageReporter
+	limiter *RateLimiter
+}
+
+// WithUsageThrottling decorates the usage reporter, so each usage request waits for the given rate limiter.
+func WithUsageThrottling(next UsageReporter, limiter *RateLimiter) UsageReporter {
+	return &rateLimitedUsageReporter{next: next, limiter: limiter}
+}
+
+// GetUsageWithContext waits for the rate limiter and calls the decorated reporter.
+func (reporter *rateLimitedUsageReporter) GetUsageWithContext(ctx context.Context) (resp *UsageResponse, err error) {
+	err = limitCall(ctx, reporter.limiter, 0, func() (err error) {
+		resp, err = reporter.next.GetUsageWithContext(ctx)
+		return
+	})
+	return
+}
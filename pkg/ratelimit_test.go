@@ -0,0 +1,67 @@
+package deeplclient
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestRateLimiterCharacters tests whether the limiter blocks until enough characters are available.
+func TestRateLimiterCharacters(t *testing.T) {
+	limiter := NewRateLimiter(0, 10, 100*time.Millisecond)
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := limiter.Wait(context.Background(), 10); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 180*time.Millisecond {
+		t.Fatalf("limiter did not block long enough: %s", elapsed)
+	}
+}
+
+// TestRateLimiterOversizedRequest tests whether requests exceeding the burst size do not block forever.
+func TestRateLimiterOversizedRequest(t *testing.T) {
+	limiter := NewRateLimiter(0, 10, time.Hour)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := limiter.Wait(ctx, 100); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestRateLimiterContext tests whether a blocked limiter returns as soon as the context is done.
+func TestRateLimiterContext(t *testing.T) {
+	limiter := NewRateLimiter(1, 0, 0)
+	if err := limiter.Wait(context.Background(), 0); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := limiter.Wait(ctx, 0); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded error, got: %v", err)
+	}
+}
+
+// TestRateLimiterThrottle tests whether the rate is reduced after throttling and recovers afterwards.
+func TestRateLimiterThrottle(t *testing.T) {
+	limiter := NewRateLimiter(100, 0, 0)
+	limiter.SetRecoveryPeriod(100 * time.Millisecond)
+	limiter.Throttle()
+	limiter.Throttle()
+	limiter.mutex.Lock()
+	factor := limiter.factor
+	limiter.mutex.Unlock()
+	if factor < 0.25 || factor > 0.3 {
+		t.Fatalf("expected rate factor of about 0.25 after throttling twice, got %f", factor)
+	}
+	time.Sleep(150 * time.Millisecond)
+	limiter.mutex.Lock()
+	limiter.refill(time.Now())
+	factor = limiter.factor
+	limiter.mutex.Unlock()
+	if factor != 1 {
+		t.Fatalf("expected rate to recover completely, got factor %f", factor)
+	}
+}
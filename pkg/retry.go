@@ -0,0 +1,296 @@
+package deeplclient
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RetryPolicy configures how API requests which failed with a temporary error (HTTP status 429, 529 or 5xx or a
+// network error) are sent again. Requests which failed because of an exceeded quota or an invalid auth key are never
+// retried. Network errors of non-idempotent requests (e.g. translations) are only retried if the connection could not
+// be established, as the server may already have processed (and billed) a request whose response got lost.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum amount of attempts per request including the first one. Values lower than 2 disable
+	// retries.
+	MaxAttempts int
+	// BaseDelay is the delay before the first retry. It is doubled for every further attempt.
+	BaseDelay time.Duration
+	// MaxDelay caps the delay between two attempts, including delays requested by the server via the Retry-After
+	// header. Zero means no cap.
+	MaxDelay time.Duration
+	// Jitter is the fraction (between 0 and 1) of each delay which is randomized in order to prevent concurrent
+	// clients from retrying in lockstep.
+	Jitter float64
+}
+
+// DefaultRetryPolicy returns a retry policy with reasonable defaults for most applications.
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts: 5,
+		BaseDelay:   500 * time.Millisecond,
+		MaxDelay:    30 * time.Second,
+		Jitter:      0.2,
+	}
+}
+
+// validate returns an error if the policy contains invalid values.
+func (policy *RetryPolicy) validate() error {
+	switch {
+	case policy.MaxAttempts < 0:
+		return errors.New("maximum attempts of retry policy cannot be negative")
+	case policy.BaseDelay < 0 || policy.MaxDelay < 0:
+		return errors.New("delays of retry policy cannot be negative")
+	case policy.Jitter < 0 || policy.Jitter > 1:
+		return errors.New("jitter of retry policy has to be between 0 and 1")
+	}
+	return nil
+}
+
+// attempts returns the maximum amount of attempts per request. A nil policy allows exactly one attempt.
+func (policy *RetryPolicy) attempts() int {
+	if policy == nil || policy.MaxAttempts < 1 {
+		return 1
+	}
+	return policy.MaxAttempts
+}
+
+// delay calculates the time to wait after the given (1-based) failed attempt. A delay requested by the server via the
+// Retry-After header in the given header takes precedence over the exponential backoff.
+func (policy *RetryPolicy) delay(attempt int, header http.Header) time.Duration {
+	if policy == nil {
+		return 0
+	}
+	delay, ok := retryAfter(header)
+	if !ok {
+		delay = policy.BaseDelay
+		for i := 1; i < attempt && (policy.MaxDelay <= 0 || delay < policy.MaxDelay); i++ {
+			// the delay saturates instead of overflowing, which would result in retries without backoff
+			if delay > math.MaxInt64/2 {
+				delay = math.MaxInt64
+				break
+			}
+			delay *= 2
+		}
+		if policy.Jitter > 0 {
+			jitter := policy.Jitter
+			if jitter > 1 {
+				jitter = 1
+			}
+			delay -= time.Duration(float64(delay) * jitter * rand.Float64())
+		}
+	}
+	if policy.MaxDelay > 0 && delay > policy.MaxDelay {
+		delay = policy.MaxDelay
+	}
+	return delay
+}
+
+// retryAfter parses the Retry-After header, which may either contain the amount of seconds to wait or a HTTP date.
+func retryAfter(header http.Header) (time.Duration, bool) {
+	value := header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if delay := time.Until(date); delay > 0 {
+			return delay, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// isRetryableStatus returns whether the given HTTP status code indicates a temporary error.
+func isRetryableStatus(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode >= http.StatusInternalServerError
+}
+
+// isTooManyRequestsStatus returns whether the given HTTP status code indicates that too many requests have been sent.
+func isTooManyRequestsStatus(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode == StatusTooManyRequestsHighLoad
+}
+
+// sleepContext waits for the given duration or until the context is done, whichever happens first.
+func sleepContext(ctx context.Context, delay time.Duration) error {
+	if delay <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// isRetryableErr returns whether the error returned by a translator indicates a temporary error.
+func isRetryableErr(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var apiErr *APIError
+	var urlErr *url.Error
+	switch {
+	case errors.As(err, &apiErr):
+		return apiErr.Retryable()
+	case errors.As(err, &urlErr):
+		return isRetryableNetworkErr(err)
+	default:
+		return false
+	}
+}
+
+// isRetryableNetworkErr returns whether the network error allows sending the request again without processing it
+// twice. This is the case for idempotent requests and requests which have not been sent at all.
+func isRetryableNetworkErr(err error) bool {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) && isIdempotentMethod(strings.ToUpper(urlErr.Op)) {
+		return true
+	}
+	// the connection could not be established, so the request has not been sent
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// isIdempotentMethod returns whether requests with the given HTTP method can be sent several times safely.
+func isIdempotentMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// errHeader returns the response header of an API error, so a delay requested by the server can be honoured.
+func errHeader(err error) http.Header {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Header
+	}
+	return nil
+}
+
+// retry calls the function until it succeeds, fails with a permanent error, the maximum amount of attempts is reached
+// or the context is done.
+func (policy *RetryPolicy) retry(ctx context.Context, f func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := f()
+		if err == nil || attempt >= policy.attempts() || !isRetryableErr(ctx, err) {
+			return err
+		}
+		if err = sleepContext(ctx, policy.delay(attempt, errHeader(err))); err != nil {
+			return err
+		}
+	}
+}
+
+// WithRetries decorates the translator, so calls failing with a temporary error are retried according to the given
+// policy. In contrast to the retry policy of a client (see WithRetryPolicy), which retries single HTTP requests, the
+// decorator retries whole calls of any translator. See WithTextRetries, WithDocumentRetries and WithUsageRetries for
+// translators implementing only a part of the Translator interface.
+func WithRetries(next Translator, policy *RetryPolicy) Translator {
+	return &translatorSet{
+		TextTranslator:     WithTextRetries(next, policy),
+		DocumentTranslator: WithDocumentRetries(next, policy),
+		UsageReporter:      WithUsageRetries(next, policy),
+	}
+}
+
+// retryTextTranslator is a decorator which retries text translations failing with a temporary error.
+type retryTextTranslator struct {
+	next   TextTranslator
+	policy *RetryPolicy
+}
+
+// WithTextRetries decorates the text translator, so translations failing with a temporary error are retried according
+// to the given policy.
+func WithTextRetries(next TextTranslator, policy *RetryPolicy) TextTranslator {
+	return &retryTextTranslator{next: next, policy: policy}
+}
+
+// TranslateWithContext calls the decorated translator and retries the call on temporary errors.
+func (translator *retryTextTranslator) TranslateWithContext(ctx context.Context, req *TranslationRequest) (
+	resp *TranslationResponse, err error) {
+	err = translator.policy.retry(ctx, func() (err error) {
+		resp, err = translator.next.TranslateWithContext(ctx, req)
+		return
+	})
+	return
+}
+
+// retryDocumentTranslator is a decorator which retries document API calls failing with a temporary error.
+type retryDocumentTranslator struct {
+	next   DocumentTranslator
+	policy *RetryPolicy
+}
+
+// WithDocumentRetries decorates the document translator, so calls failing with a temporary error are retried
+// according to the given policy.
+func WithDocumentRetries(next DocumentTranslator, policy *RetryPolicy) DocumentTranslator {
+	return &retryDocumentTranslator{next: next, policy: policy}
+}
+
+// StartDocumentTranslateWithContext calls the decorated translator and retries the call on temporary errors.
+func (translator *retryDocumentTranslator) StartDocumentTranslateWithContext(ctx context.Context,
+	req *DocumentTranslationStartRequest) (resp *DocumentTranslationStartResponse, err error) {
+	err = translator.policy.retry(ctx, func() (err error) {
+		resp, err = translator.next.StartDocumentTranslateWithContext(ctx, req)
+		return
+	})
+	return
+}
+
+// CheckDocumentTranslationStatusWithContext calls the decorated translator and retries the call on temporary errors.
+func (translator *retryDocumentTranslator) CheckDocumentTranslationStatusWithContext(ctx context.Context,
+	req *DocumentTranslationStatusRequest) (resp *DocumentTranslationStatusResponse, err error) {
+	err = translator.policy.retry(ctx, func() (err error) {
+		resp, err = translator.next.CheckDocumentTranslationStatusWithContext(ctx, req)
+		return
+	})
+	return
+}
+
+// DownloadTranslatedDocumentWithContext calls the decorated translator and retries the call on temporary errors.
+func (translator *retryDocumentTranslator) DownloadTranslatedDocumentWithContext(ctx context.Context,
+	req *DocumentTranslationDownloadRequest) (result []byte, err error) {
+	err = translator.policy.retry(ctx, func() (err error) {
+		result, err = translator.next.DownloadTranslatedDocumentWithContext(ctx, req)
+		return
+	})
+	return
+}
+
+// retryUsageReporter is a decorator which retries usage requests failing with a temporary error.
+type retryUsageReporter struct {
+	next   UsageReporter
+	policy *RetryPolicy
+}
+
+// WithUsageRetries decorates the usage reporter, so usage requests failing with a temporary error are retried
+// according to the given policy.
+func WithUsageRetries(next UsageReporter, policy *RetryPolicy) UsageReporter {
+	return &retryUsageReporter{next: next, policy: policy}
+}
+
+// GetUsageWithContext calls the decorated reporter and retries the call on temporary errors.
+func (reporter *retryUsageReporter) GetUsageWithContext(ctx context.Context) (resp *UsageResponse, err error) {
+	err = reporter.policy.retry(ctx, func() (err error) {
+		resp, err = reporter.next.GetUsageWithContext(ctx)
+		return
+	})
+	return
+}
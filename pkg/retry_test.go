@@ -0,0 +1,166 @@
+package deeplclient
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newRetryTestClient creates a client which retries immediately and sends all requests to the given server.
+func newRetryTestClient(server *httptest.Server) *Client {
+	return &Client{
+		Client:      server.Client(),
+		AuthKey:     []byte("test"),
+		EndpointUrl: server.URL + "/v2/",
+		retryPolicy: &RetryPolicy{MaxAttempts: 3},
+	}
+}
+
+// TestRetryTemporaryErrors tests whether requests are replayed after temporary errors.
+func TestRetryTemporaryErrors(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		switch attempts {
+		case 1:
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+		case 2:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			_, _ = w.Write([]byte(`{"character_count":42,"character_limit":500000}`))
+		}
+	}))
+	defer server.Close()
+	resp, err := newRetryTestClient(server).GetUsage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attempts != 3 || resp.CharacterCount != 42 {
+		t.Fatalf("unexpected result after %d attempts: %+v", attempts, resp)
+	}
+}
+
+// TestRetryNotOnQuotaExceeded tests whether requests failing with an exceeded quota are not replayed.
+func TestRetryNotOnQuotaExceeded(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(StatusQuotaExceeded)
+		_, _ = w.Write([]byte(`{"message":"Quota Exceeded"}`))
+	}))
+	defer server.Close()
+	_, err := newRetryTestClient(server).GetUsage()
+	if _, ok := err.(*QuotaExceededErr); !ok {
+		t.Fatalf("expected quota exceeded error, got: %v", err)
+	}
+	if attempts != 1 {
+		t.Fatalf("request has been sent %d times", attempts)
+	}
+}
+
+// TestRetryMultipartBody tests whether the multipart body of a document upload is sent again completely.
+func TestRetryMultipartBody(t *testing.T) {
+	var mutex sync.Mutex
+	var bodies [][]byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mutex.Lock()
+		bodies = append(bodies, body)
+		attempt := len(bodies)
+		mutex.Unlock()
+		if attempt == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		_, _ = w.Write([]byte(`{"document_id":"id","document_key":"key"}`))
+	}))
+	defer server.Close()
+	resp, err := newRetryTestClient(server).StartDocumentTranslate(&DocumentTranslationStartRequest{
+		TargetLang: LangDE,
+		File:       []byte("Hello world!"),
+		Filename:   "hello.txt",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.DocumentId != "id" || len(bodies) != 2 || len(bodies[0]) == 0 || !bytes.Equal(bodies[0], bodies[1]) {
+		t.Fatalf("multipart body has not been replayed correctly: %+v", resp)
+	}
+}
+
+// TestRetryPolicyDelay tests the calculation of delays between attempts.
+func TestRetryPolicyDelay(t *testing.T) {
+	policy := &RetryPolicy{MaxAttempts: 5, BaseDelay: time.Second, MaxDelay: 3 * time.Second}
+	for attempt, expected := range []time.Duration{time.Second, 2 * time.Second, 3 * time.Second, 3 * time.Second} {
+		if delay := policy.delay(attempt+1, nil); delay != expected {
+			t.Errorf("attempt %d: expected delay %s, got %s", attempt+1, expected, delay)
+		}
+	}
+	header := http.Header{}
+	header.Set("Retry-After", "2")
+	if delay := policy.delay(1, header); delay != 2*time.Second {
+		t.Errorf("Retry-After header has not been honored, got %s", delay)
+	}
+	// without cap, the delay must grow monotonically instead of overflowing
+	uncapped := &RetryPolicy{MaxAttempts: 100, BaseDelay: 500 * time.Millisecond}
+	previous := time.Duration(0)
+	for attempt := 1; attempt <= uncapped.MaxAttempts; attempt++ {
+		delay := uncapped.delay(attempt, nil)
+		if delay < previous {
+			t.Fatalf("attempt %d: delay %s is less than previous delay %s", attempt, delay, previous)
+		}
+		previous = delay
+	}
+	if previous != math.MaxInt64 {
+		t.Fatalf("expected saturated delay, got %s", previous)
+	}
+}
+
+// TestRetryNetworkErrors tests whether only idempotent requests are replayed after their response got lost, as the
+// server may have processed the request already.
+func TestRetryNetworkErrors(t *testing.T) {
+	var mutex sync.Mutex
+	attempts := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		attempts[r.Method]++
+		mutex.Unlock()
+		// abort the connection without response
+		panic(http.ErrAbortHandler)
+	}))
+	defer server.Close()
+	client := newRetryTestClient(server)
+	if _, err := client.Translate(&TranslationRequest{Text: "Hallo", TargetLang: LangENGB}); err == nil {
+		t.Fatal("expected network error")
+	}
+	if _, err := client.GetUsage(); err == nil {
+		t.Fatal("expected network error")
+	}
+	mutex.Lock()
+	defer mutex.Unlock()
+	if attempts[http.MethodPost] != 1 || attempts[http.MethodGet] != 3 {
+		t.Fatalf("unexpected attempts: %v", attempts)
+	}
+
+	// requests which could not be sent at all are always replayed
+	server.Close()
+	if !isRetryableNetworkErr(sendClosed(t, server.URL)) {
+		t.Fatal("expected dial error to be retryable")
+	}
+}
+
+// sendClosed sends a POST request to the closed server and returns the resulting error.
+func sendClosed(t *testing.T, serverUrl string) error {
+	resp, err := http.Post(serverUrl, "text/plain", bytes.NewReader(nil))
+	if err == nil {
+		_ = resp.Body.Close()
+		t.Fatal("expected error for closed server")
+	}
+	return err
+}
@@ -0,0 +1,219 @@
+package deeplclient
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// split levels ordered from the most to the least preferred boundaries
+const (
+	splitParagraphs = iota
+	splitSentences
+	splitWords
+	splitRunes
+)
+
+// blockClosingTags contains tags which end a paragraph if tag handling is enabled.
+var blockClosingTags = []string{"</p>", "</div>", "</li>", "</ul>", "</ol>", "</table>", "</tr>", "</h1>", "</h2>",
+	"</h3>", "</h4>", "</h5>", "</h6>", "</section>", "</article>", "<br>", "<br/>", "<br />"}
+
+// maxBlockClosingTagLength is the length of the longest tag within blockClosingTags.
+const maxBlockClosingTagLength = len("</article>")
+
+// textSplitter splits texts into pieces which are small enough to be sent within a single request.
+type textSplitter struct {
+	// maxSize is the maximum url encoded size of each piece
+	maxSize int
+	// tagAware defines whether markup tags should be considered
+	tagAware bool
+}
+
+// fits returns whether the url encoded text does not exceed the maximum size of a piece.
+func (splitter *textSplitter) fits(text string) bool {
+	return encodedSize(text) <= splitter.maxSize
+}
+
+// encodedSize returns the size of the url encoded text. As every character is encoded on its own, the encoded size of
+// concatenated texts equals the sum of their encoded sizes.
+func encodedSize(text string) int {
+	return len(url.QueryEscape(text))
+}
+
+// split splits the text into pieces which fit into a request. Paragraph boundaries are preferred over sentence
+// boundaries, which are preferred over word boundaries. The concatenation of all pieces equals the text.
+func (splitter *textSplitter) split(text string) []string {
+	return splitter.splitLevel(text, splitParagraphs)
+}
+
+// splitLevel splits the text at the boundaries of the given level and merges neighbouring segments as long as they
+// fit into one piece. Segments which are too large on their own are split at the boundaries of the next level.
+func (splitter *textSplitter) splitLevel(text string, level int) (pieces []string) {
+	if splitter.fits(text) {
+		return []string{text}
+	}
+	if level == splitRunes {
+		return splitter.hardSplit(text)
+	}
+	var current strings.Builder
+	currentSize := 0
+	for _, segment := range splitter.segments(text, level) {
+		size := encodedSize(segment)
+		if size > splitter.maxSize {
+			if current.Len() > 0 {
+				pieces = append(pieces, current.String())
+				current.Reset()
+				currentSize = 0
+			}
+			pieces = append(pieces, splitter.splitLevel(segment, level+1)...)
+			continue
+		}
+		if current.Len() > 0 && currentSize+size > splitter.maxSize {
+			pieces = append(pieces, current.String())
+			current.Reset()
+			currentSize = 0
+		}
+		current.WriteString(segment)
+		currentSize += size
+	}
+	if current.Len() > 0 {
+		pieces = append(pieces, current.String())
+	}
+	return
+}
+
+// hardSplit splits the text into pieces which are as large as possible regardless of any boundaries. If the splitter
+// is tag aware, markup tags are not cut as long as possible.
+func (splitter *textSplitter) hardSplit(text string) (pieces []string) {
+	for len(text) > 0 {
+		end, size, tagStart := 0, 0, -1
+		for i, r := range text {
+			next := i + utf8.RuneLen(r)
+			if size += encodedSize(text[i:next]); size > splitter.maxSize {
+				break
+			}
+			end = next
+			if r == '<' {
+				tagStart = i
+			} else if r == '>' {
+				tagStart = -1
+			}
+		}
+		if splitter.tagAware && tagStart > 0 && end < len(text) {
+			end = tagStart
+		}
+		if end == 0 {
+			// always make progress, even if a single character exceeds the maximum size
+			_, end = utf8.DecodeRuneInString(text)
+		}
+		pieces = append(pieces, text[:end])
+		text = text[end:]
+	}
+	return
+}
+
+// segments cuts the text into consecutive segments ending at the boundaries of the given level. Boundaries within
+// markup tags are skipped if the splitter is tag aware.
+func (splitter *textSplitter) segments(text string, level int) (segments []string) {
+	insideTag := false
+	start := 0
+	for i, r := range text {
+		if splitter.tagAware {
+			if r == '<' {
+				insideTag = true
+			} else if r == '>' {
+				insideTag = false
+			}
+		}
+		end := i + utf8.RuneLen(r)
+		if insideTag || end == len(text) || !splitter.isBoundary(text, end, level) {
+			continue
+		}
+		segments = append(segments, text[start:end])
+		start = end
+	}
+	if start < len(text) {
+		segments = append(segments, text[start:])
+	}
+	return
+}
+
+// isBoundary returns whether the text may be split right before the given byte position at the given level.
+func (splitter *textSplitter) isBoundary(text string, pos int, level int) bool {
+	previous, _ := utf8.DecodeLastRuneInString(text[:pos])
+	next, _ := utf8.DecodeRuneInString(text[pos:])
+	switch level {
+	case splitParagraphs:
+		if splitter.tagAware && previous == '>' {
+			tail := text[:pos]
+			if len(tail) > maxBlockClosingTagLength {
+				tail = tail[len(tail)-maxBlockClosingTagLength:]
+			}
+			tail = strings.ToLower(tail)
+			for _, tag := range blockClosingTags {
+				if strings.HasSuffix(tail, tag) {
+					return true
+				}
+			}
+		}
+		// paragraphs end after an empty line
+		return previous == '\n' && next != '\n' && strings.HasSuffix(strings.TrimRight(text[:pos], " \t\r"), "\n\n")
+	case splitSentences:
+		// full-width punctuation is not followed by whitespace
+		if strings.ContainsRune("。！？", previous) {
+			return true
+		}
+		if !unicode.IsSpace(previous) || unicode.IsSpace(next) {
+			return false
+		}
+		last, _ := utf8.DecodeLastRuneInString(strings.TrimRightFunc(text[:pos], unicode.IsSpace))
+		return strings.ContainsRune(".!?", last)
+	case splitWords:
+		return unicode.IsSpace(previous) && !unicode.IsSpace(next)
+	default:
+		return true
+	}
+}
+
+// translateSplit translates an oversized text by splitting it into pieces, translating them as a batch and joining
+// the translated pieces again. The whitespace between the pieces is preserved and the detected source language is
+// the one detected for the largest part of the text.
+func (client *Client) translateSplit(ctx context.Context, req *TranslationRequest, values *url.Values) (
+	resp *TranslationResponse, err error) {
+	splitter := &textSplitter{
+		// each piece is sent as an additional "&text=..." parameter
+		maxSize:  maxBodySize - len(values.Encode()) - len("&text="),
+		tagAware: len(req.TagHandling) > 0,
+	}
+	pieces := splitter.split(req.Text)
+	contents := make([]string, len(pieces))
+	for i, piece := range pieces {
+		contents[i] = strings.TrimSpace(piece)
+	}
+	translations, err := client.TranslateBatchWithContext(ctx, req, contents)
+	if err != nil {
+		return nil, err
+	}
+
+	var text strings.Builder
+	languageWeights := map[ApiLang]int{}
+	for i, piece := range pieces {
+		// keep the original whitespace around each piece
+		text.WriteString(piece[:strings.Index(piece, contents[i])])
+		text.WriteString(translations[i].Text)
+		text.WriteString(piece[strings.Index(piece, contents[i])+len(contents[i]):])
+		if translations[i].DetectedSourceLanguage != "" {
+			languageWeights[translations[i].DetectedSourceLanguage] += len(contents[i])
+		}
+	}
+	translation := Translation{Text: text.String()}
+	for lang, weight := range languageWeights {
+		if weight > languageWeights[translation.DetectedSourceLanguage] ||
+			(weight == languageWeights[translation.DetectedSourceLanguage] && lang < translation.DetectedSourceLanguage) {
+			translation.DetectedSourceLanguage = lang
+		}
+	}
+	return &TranslationResponse{Translations: []Translation{translation}}, nil
+}
@@ -0,0 +1,98 @@
+package deeplclient
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestTextSplitterBoundaries tests whether texts are split at the most preferred boundaries which are possible.
+func TestTextSplitterBoundaries(t *testing.T) {
+	tests := []struct {
+		name     string
+		splitter *textSplitter
+		text     string
+		expected []string
+	}{
+		{
+			name:     "paragraphs",
+			splitter: &textSplitter{maxSize: 30},
+			text:     "First paragraph.\n\nSecond paragraph.\n\nThird.",
+			expected: []string{"First paragraph.\n\n", "Second paragraph.\n\nThird."},
+		},
+		{
+			name:     "sentences",
+			splitter: &textSplitter{maxSize: 20},
+			text:     "One sentence. Another sentence! A third?",
+			expected: []string{"One sentence. ", "Another sentence! ", "A third?"},
+		},
+		{
+			name:     "words",
+			splitter: &textSplitter{maxSize: 10},
+			text:     "abc def ghi jkl",
+			expected: []string{"abc def ", "ghi jkl"},
+		},
+		{
+			name:     "runes",
+			splitter: &textSplitter{maxSize: 4},
+			text:     "abcdefghij",
+			expected: []string{"abcd", "efgh", "ij"},
+		},
+		{
+			name:     "tags",
+			splitter: &textSplitter{maxSize: 30, tagAware: true},
+			text:     "<p>Hello world</p><p>Good bye</p>",
+			expected: []string{"<p>Hello world</p>", "<p>Good bye</p>"},
+		},
+		{
+			name:     "tags without boundaries",
+			splitter: &textSplitter{maxSize: 14, tagAware: true},
+			text:     "abcdefghij<b>kl</b>",
+			expected: []string{"abcdefghij", "<b>kl", "</b>"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pieces := test.splitter.split(test.text)
+			if strings.Join(pieces, "") != test.text {
+				t.Fatalf("pieces do not form the original text: %q", pieces)
+			}
+			if len(pieces) != len(test.expected) {
+				t.Fatalf("expected %q, got %q", test.expected, pieces)
+			}
+			for i := range pieces {
+				if pieces[i] != test.expected[i] {
+					t.Fatalf("expected %q, got %q", test.expected, pieces)
+				}
+			}
+		})
+	}
+}
+
+// TestTranslateSplitOversizedText tests whether oversized texts are translated piece by piece and joined again.
+func TestTranslateSplitOversizedText(t *testing.T) {
+	var requests int32
+	server := newEchoTranslationServer(t, &requests)
+	defer server.Close()
+	client := &Client{
+		Client:              server.Client(),
+		AuthKey:             []byte("test"),
+		EndpointUrl:         server.URL + "/v2/",
+		splitOversizedTexts: true,
+	}
+	paragraph := strings.Repeat("This is a sentence. ", maxBodySize/30)
+	text := strings.TrimSpace(paragraph) + "\n\n" + strings.TrimSpace(paragraph) + "\n\n" + paragraph
+	resp, err := client.Translate(&TranslationRequest{Text: text, TargetLang: LangENUS})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Translations) != 1 || resp.Translations[0].DetectedSourceLanguage != LangDE {
+		t.Fatalf("unexpected translation response: %+v", resp.Translations)
+	}
+	translated := strings.Split(resp.Translations[0].Text, "\n\n")
+	if len(translated) != 3 || !strings.HasPrefix(translated[1], "EN-US:This is a sentence.") {
+		t.Fatalf("paragraphs have not been preserved")
+	}
+	if requests < 2 {
+		t.Fatalf("expected text to be split into several requests, got %d", requests)
+	}
+}
@@ -0,0 +1,601 @@
+package deeplclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// tenantPeriodLayout is the layout of the monthly accounting periods of tenants
+	tenantPeriodLayout = "2006-01"
+	// defaultPendingDocumentTTL is the default time after which pending documents of tenants are assumed to be
+	// abandoned
+	defaultPendingDocumentTTL = 24 * time.Hour
+)
+
+// ErrTenantRequired is returned if a tenant ledger requires a tenant, but the context of the request does not contain
+// any (see WithTenant).
+var ErrTenantRequired = errors.New("deepl request does not belong to any tenant")
+
+// tenantContextKey is the key of the tenant within a context.
+type tenantContextKey struct{}
+
+// WithTenant returns a copy of the context which attributes all requests made with it to the given tenant.
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantContextKey{}, tenant)
+}
+
+// TenantFromContext returns the tenant set by WithTenant. The second return value is false if the context does not
+// contain a tenant.
+func TenantFromContext(ctx context.Context) (string, bool) {
+	tenant, ok := ctx.Value(tenantContextKey{}).(string)
+	return tenant, ok && tenant != ""
+}
+
+// TenantUsage contains the usage of a tenant within a monthly accounting period.
+type TenantUsage struct {
+	// Tenant is the identifier of the tenant.
+	Tenant string `json:"tenant"`
+	// Period is the calendar month (in UTC) of the usage, formatted as "2006-01".
+	Period string `json:"period"`
+	// Characters is the amount of characters of translated texts.
+	Characters int64 `json:"characters"`
+	// BilledCharacters is the amount of characters billed for translated documents.
+	BilledCharacters int64 `json:"billed_characters"`
+	// Documents is the amount of uploaded documents.
+	Documents int64 `json:"documents"`
+}
+
+// Total returns the amount of characters counting towards the budget of the tenant.
+func (usage TenantUsage) Total() int64 {
+	return usage.Characters + usage.BilledCharacters
+}
+
+// add adds the counters of the delta to the usage.
+func (usage *TenantUsage) add(delta TenantUsage) {
+	usage.Characters += delta.Characters
+	usage.BilledCharacters += delta.BilledCharacters
+	usage.Documents += delta.Documents
+}
+
+// LedgerStore stores the usage of all tenants. Implementations must be safe for concurrent use.
+type LedgerStore interface {
+	// Get returns the usage of the tenant within the period, which is zero if nothing has been recorded yet.
+	Get(tenant, period string) (TenantUsage, error)
+	// Add adds the counters of the delta to the usage of the tenant within the period and returns the new usage.
+	Add(tenant, period string, delta TenantUsage) (TenantUsage, error)
+	// Entries returns the usage of all tenants within all periods sorted by period and tenant.
+	Entries() ([]TenantUsage, error)
+	// AddPendingDocument stores the uploaded document, whose billed characters have not been recorded yet.
+	AddPendingDocument(document PendingDocument) error
+	// TakePendingDocument removes the pending document with the given ID and returns it. The second return value is
+	// false if no such document is pending.
+	TakePendingDocument(documentId string) (PendingDocument, bool, error)
+	// ExpirePendingDocuments removes all pending documents which have been uploaded before the given time.
+	ExpirePendingDocuments(before time.Time) error
+}
+
+// PendingDocument is an uploaded document of a tenant, whose billed characters are recorded once its translation is
+// finished.
+type PendingDocument struct {
+	// DocumentId is the unique ID of the document.
+	DocumentId string `json:"document_id"`
+	// Tenant is the tenant which uploaded the document.
+	Tenant string `json:"tenant"`
+	// Period is the accounting period of the upload, which the billed characters are recorded in.
+	Period string `json:"period"`
+	// Uploaded is the time of the upload.
+	Uploaded time.Time `json:"uploaded"`
+}
+
+// tenantPeriod identifies the usage of a tenant within a period.
+type tenantPeriod struct {
+	tenant string
+	period string
+}
+
+// MemoryLedgerStore is an in-memory LedgerStore, whose usage and pending documents are lost when the process exits.
+type MemoryLedgerStore struct {
+	mutex   sync.Mutex
+	entries map[tenantPeriod]TenantUsage
+	pending map[string]PendingDocument
+}
+
+// NewMemoryLedgerStore creates a new empty in-memory ledger store.
+func NewMemoryLedgerStore() *MemoryLedgerStore {
+	return &MemoryLedgerStore{entries: map[tenantPeriod]TenantUsage{}, pending: map[string]PendingDocument{}}
+}
+
+// Get returns the usage of the tenant within the period.
+func (store *MemoryLedgerStore) Get(tenant, period string) (TenantUsage, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	return store.get(tenant, period), nil
+}
+
+// get returns the usage of the tenant within the period. The mutex has to be held by the caller.
+func (store *MemoryLedgerStore) get(tenant, period string) TenantUsage {
+	usage, ok := store.entries[tenantPeriod{tenant, period}]
+	if !ok {
+		usage = TenantUsage{Tenant: tenant, Period: period}
+	}
+	return usage
+}
+
+// Add adds the counters of the delta to the usage of the tenant within the period.
+func (store *MemoryLedgerStore) Add(tenant, period string, delta TenantUsage) (TenantUsage, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	return store.add(tenant, period, delta), nil
+}
+
+// add adds the counters of the delta to the usage of the tenant within the period. The mutex has to be held by the
+// caller.
+func (store *MemoryLedgerStore) add(tenant, period string, delta TenantUsage) TenantUsage {
+	usage := store.get(tenant, period)
+	usage.add(delta)
+	store.entries[tenantPeriod{tenant, period}] = usage
+	return usage
+}
+
+// Entries returns the usage of all tenants within all periods.
+func (store *MemoryLedgerStore) Entries() ([]TenantUsage, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	return store.sortedEntries(), nil
+}
+
+// sortedEntries returns all entries sorted by period and tenant. The mutex has to be held by the caller.
+func (store *MemoryLedgerStore) sortedEntries() []TenantUsage {
+	entries := make([]TenantUsage, 0, len(store.entries))
+	for _, usage := range store.entries {
+		entries = append(entries, usage)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Period != entries[j].Period {
+			return entries[i].Period < entries[j].Period
+		}
+		return entries[i].Tenant < entries[j].Tenant
+	})
+	return entries
+}
+
+// AddPendingDocument stores the uploaded document.
+func (store *MemoryLedgerStore) AddPendingDocument(document PendingDocument) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	store.pending[document.DocumentId] = document
+	return nil
+}
+
+// TakePendingDocument removes the pending document with the given ID and returns it.
+func (store *MemoryLedgerStore) TakePendingDocument(documentId string) (PendingDocument, bool, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	document, ok := store.pending[documentId]
+	delete(store.pending, documentId)
+	return document, ok, nil
+}
+
+// ExpirePendingDocuments removes all pending documents which have been uploaded before the given time.
+func (store *MemoryLedgerStore) ExpirePendingDocuments(before time.Time) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	store.expirePendingDocuments(before)
+	return nil
+}
+
+// expirePendingDocuments removes the pending documents uploaded before the given time and returns them. The mutex has
+// to be held by the caller.
+func (store *MemoryLedgerStore) expirePendingDocuments(before time.Time) []PendingDocument {
+	var expired []PendingDocument
+	for documentId, document := range store.pending {
+		if document.Uploaded.Before(before) {
+			expired = append(expired, document)
+			delete(store.pending, documentId)
+		}
+	}
+	return expired
+}
+
+// sortedPendingDocuments returns all pending documents sorted by their IDs. The mutex has to be held by the caller.
+func (store *MemoryLedgerStore) sortedPendingDocuments() []PendingDocument {
+	documents := make([]PendingDocument, 0, len(store.pending))
+	for _, document := range store.pending {
+		documents = append(documents, document)
+	}
+	sort.Slice(documents, func(i, j int) bool {
+		return documents[i].DocumentId < documents[j].DocumentId
+	})
+	return documents
+}
+
+// FileLedgerStore is a persistent LedgerStore, which keeps the usage of all tenants and their pending documents in
+// memory and writes them into a single JSON file after every change. The file must not be shared by several processes.
+type FileLedgerStore struct {
+	memory *MemoryLedgerStore
+	path   string
+}
+
+// ledgerFile is the content of the file of a FileLedgerStore.
+type ledgerFile struct {
+	Usage            []TenantUsage     `json:"usage"`
+	PendingDocuments []PendingDocument `json:"pending_documents"`
+}
+
+// NewFileLedgerStore creates a new persistent ledger store using the given file, whose usage and pending documents are
+// loaded if it already exists.
+func NewFileLedgerStore(path string) (*FileLedgerStore, error) {
+	store := &FileLedgerStore{memory: NewMemoryLedgerStore(), path: path}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return store, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var content ledgerFile
+	if err = json.Unmarshal(data, &content); err != nil {
+		return nil, fmt.Errorf("could not parse ledger file %s: %w", path, err)
+	}
+	for _, usage := range content.Usage {
+		store.memory.add(usage.Tenant, usage.Period, usage)
+	}
+	for _, document := range content.PendingDocuments {
+		store.memory.pending[document.DocumentId] = document
+	}
+	return store, nil
+}
+
+// Get returns the usage of the tenant within the period.
+func (store *FileLedgerStore) Get(tenant, period string) (TenantUsage, error) {
+	return store.memory.Get(tenant, period)
+}
+
+// Add adds the counters of the delta to the usage of the tenant within the period and writes the file. The change is
+// reverted if the file could not be written.
+func (store *FileLedgerStore) Add(tenant, period string, delta TenantUsage) (TenantUsage, error) {
+	store.memory.mutex.Lock()
+	defer store.memory.mutex.Unlock()
+	usage := store.memory.add(tenant, period, delta)
+	if err := store.save(); err != nil {
+		store.memory.add(tenant, period, TenantUsage{
+			Characters:       -delta.Characters,
+			BilledCharacters: -delta.BilledCharacters,
+			Documents:        -delta.Documents,
+		})
+		return store.memory.get(tenant, period), err
+	}
+	return usage, nil
+}
+
+// Entries returns the usage of all tenants within all periods.
+func (store *FileLedgerStore) Entries() ([]TenantUsage, error) {
+	return store.memory.Entries()
+}
+
+// AddPendingDocument stores the uploaded document and writes the file. The change is reverted if the file could not
+// be written.
+func (store *FileLedgerStore) AddPendingDocument(document PendingDocument) error {
+	store.memory.mutex.Lock()
+	defer store.memory.mutex.Unlock()
+	store.memory.pending[document.DocumentId] = document
+	if err := store.save(); err != nil {
+		delete(store.memory.pending, document.DocumentId)
+		return err
+	}
+	return nil
+}
+
+// TakePendingDocument removes the pending document with the given ID, writes the file and returns the document. The
+// change is reverted if the file could not be written.
+func (store *FileLedgerStore) TakePendingDocument(documentId string) (PendingDocument, bool, error) {
+	store.memory.mutex.Lock()
+	defer store.memory.mutex.Unlock()
+	document, ok := store.memory.pending[documentId]
+	if !ok {
+		return document, false, nil
+	}
+	delete(store.memory.pending, documentId)
+	if err := store.save(); err != nil {
+		store.memory.pending[documentId] = document
+		return document, false, err
+	}
+	return document, true, nil
+}
+
+// ExpirePendingDocuments removes all pending documents which have been uploaded before the given time and writes the
+// file if any document has been removed. The change is reverted if the file could not be written.
+func (store *FileLedgerStore) ExpirePendingDocuments(before time.Time) error {
+	store.memory.mutex.Lock()
+	defer store.memory.mutex.Unlock()
+	expired := store.memory.expirePendingDocuments(before)
+	if len(expired) == 0 {
+		return nil
+	}
+	if err := store.save(); err != nil {
+		for _, document := range expired {
+			store.memory.pending[document.DocumentId] = document
+		}
+		return err
+	}
+	return nil
+}
+
+// save writes all entries and pending documents into the file. The mutex of the in-memory store has to be held by
+// the caller.
+func (store *FileLedgerStore) save() error {
+	data, err := json.MarshalIndent(ledgerFile{
+		Usage:            store.memory.sortedEntries(),
+		PendingDocuments: store.memory.sortedPendingDocuments(),
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	// write into a temporary file first, so the ledger is never left partially written
+	file, err := os.CreateTemp(filepath.Dir(store.path), ".tmp-ledger-*")
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(data)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(file.Name(), store.path)
+	}
+	if err != nil {
+		_ = os.Remove(file.Name())
+	}
+	return err
+}
+
+// TenantBudgetExceededErr indicates that a request has been rejected on the client side, because it would exceed the
+// monthly budget of its tenant. It matches ErrQuotaExceeded using errors.Is.
+type TenantBudgetExceededErr struct {
+	// Tenant is the tenant of the rejected request.
+	Tenant string
+	// Period is the accounting period of the budget.
+	Period string
+	// Budget is the amount of characters the tenant may use within the period.
+	Budget int64
+	// Used is the amount of characters the tenant has used within the period so far.
+	Used int64
+	// Requested is the amount of characters of the rejected request.
+	Requested int64
+}
+
+// Error returns a compact version of all error information in order to implement the error interface.
+func (err *TenantBudgetExceededErr) Error() string {
+	return fmt.Sprintf("character budget of tenant %q exceeded in %s: %d of %d characters used, %d requested",
+		err.Tenant, err.Period, err.Used, err.Budget, err.Requested)
+}
+
+// Is reports whether the target is ErrQuotaExceeded, so code handling the exceeded quota of the account (e.g. by
+// postponing the work) handles the exhausted budget of a single tenant in the same way.
+func (err *TenantBudgetExceededErr) Is(target error) bool {
+	return target == ErrQuotaExceeded
+}
+
+// TenantLedgerOptions configures a TenantLedger.
+type TenantLedgerOptions struct {
+	// Store stores the usage of the tenants. Defaults to a new in-memory store.
+	Store LedgerStore
+	// Budgets contains the monthly character budgets of single tenants.
+	Budgets map[string]int64
+	// DefaultBudget is the monthly character budget of all tenants not contained in Budgets. If zero, their usage is
+	// only metered.
+	DefaultBudget int64
+	// RequireTenant rejects all translations and document uploads without tenant with ErrTenantRequired. Otherwise,
+	// they are neither metered nor limited.
+	RequireTenant bool
+	// PendingDocumentTTL is the time after which an uploaded document whose translation has not been seen finished is
+	// assumed to be abandoned, so it is removed from the store without recording its billed characters. Defaults to
+	// 24 hours.
+	PendingDocumentTTL time.Duration
+}
+
+// TenantLedger meters the characters and billed characters of each tenant (see WithTenant) and enforces their monthly
+// budgets, so a single auth key can be shared by several customers. Both the characters of translated texts and the
+// characters billed for translated documents count towards the budget, which is checked before a request is sent.
+// Characters of failed translations are refunded.
+//
+// The characters of a document are only known once the server has translated it. Hence, a tenant may upload documents
+// as long as its budget is not exhausted, and the upload is kept as pending document in the store. When any client
+// using the ledger sees the translation finished, the billed characters are recorded within the period of the upload,
+// even after a restart of the process if the store is persistent. A ledger is attached to a client with
+// WithTenantLedger and is safe for concurrent use.
+type TenantLedger struct {
+	store              LedgerStore
+	defaultBudget      int64
+	requireTenant      bool
+	pendingDocumentTTL time.Duration
+	// now returns the current time, which determines the accounting period
+	now func() time.Time
+
+	mutex   sync.Mutex
+	budgets map[string]int64
+}
+
+// NewTenantLedger creates a new tenant ledger with the given options, which are validated.
+func NewTenantLedger(options TenantLedgerOptions) (*TenantLedger, error) {
+	switch {
+	case options.DefaultBudget < 0:
+		return nil, errors.New("default budget of tenant ledger cannot be negative")
+	case options.PendingDocumentTTL < 0:
+		return nil, errors.New("pending document TTL of tenant ledger cannot be negative")
+	}
+	if options.PendingDocumentTTL == 0 {
+		options.PendingDocumentTTL = defaultPendingDocumentTTL
+	}
+	budgets := make(map[string]int64, len(options.Budgets))
+	for tenant, budget := range options.Budgets {
+		if budget < 0 {
+			return nil, fmt.Errorf("budget of tenant %q cannot be negative", tenant)
+		}
+		budgets[tenant] = budget
+	}
+	store := options.Store
+	if store == nil {
+		store = NewMemoryLedgerStore()
+	}
+	return &TenantLedger{
+		store:              store,
+		defaultBudget:      options.DefaultBudget,
+		requireTenant:      options.RequireTenant,
+		pendingDocumentTTL: options.PendingDocumentTTL,
+		now:                time.Now,
+		budgets:            budgets,
+	}, nil
+}
+
+// WithTenantLedger attaches the tenant ledger to the client, so translations and documents are metered per tenant and
+// checked against their budgets. A ledger may be shared by several clients using the same auth key.
+func WithTenantLedger(ledger *TenantLedger) Option {
+	return func(client *Client) error {
+		if ledger == nil {
+			return errors.New("tenant ledger cannot be nil")
+		}
+		client.tenants = ledger
+		return nil
+	}
+}
+
+// SetBudget sets the monthly character budget of the tenant. A budget of zero removes the limit of the tenant, while a
+// negative budget resets it to the default budget.
+func (ledger *TenantLedger) SetBudget(tenant string, budget int64) {
+	ledger.mutex.Lock()
+	defer ledger.mutex.Unlock()
+	if budget < 0 {
+		delete(ledger.budgets, tenant)
+	} else {
+		ledger.budgets[tenant] = budget
+	}
+}
+
+// Budget returns the monthly character budget of the tenant, which is zero if unlimited.
+func (ledger *TenantLedger) Budget(tenant string) int64 {
+	ledger.mutex.Lock()
+	defer ledger.mutex.Unlock()
+	return ledger.budgetOf(tenant)
+}
+
+// budgetOf returns the budget of the tenant. The mutex has to be held by the caller.
+func (ledger *TenantLedger) budgetOf(tenant string) int64 {
+	if budget, ok := ledger.budgets[tenant]; ok {
+		return budget
+	}
+	return ledger.defaultBudget
+}
+
+// Usage returns the usage of the tenant within the current accounting period.
+func (ledger *TenantLedger) Usage(tenant string) (TenantUsage, error) {
+	return ledger.store.Get(tenant, ledger.period())
+}
+
+// Export returns the usage of all tenants within all periods sorted by period and tenant, e.g. for invoicing.
+func (ledger *TenantLedger) Export() ([]TenantUsage, error) {
+	return ledger.store.Entries()
+}
+
+// period returns the current accounting period.
+func (ledger *TenantLedger) period() string {
+	return ledger.now().UTC().Format(tenantPeriodLayout)
+}
+
+// reserve records the characters for the tenant of the context within the current period, unless they would exceed
+// the budget of the tenant. Document uploads, which reserve no characters, are accepted until the budget is exhausted.
+// Without a ledger or tenant, requests are neither metered nor limited (unless a tenant is required).
+func (ledger *TenantLedger) reserve(ctx context.Context, characters int64) error {
+	if ledger == nil {
+		return nil
+	}
+	tenant, ok := TenantFromContext(ctx)
+	if !ok {
+		if ledger.requireTenant {
+			return ErrTenantRequired
+		}
+		return nil
+	}
+	period := ledger.period()
+	// the check and the record have to be atomic, so concurrent requests cannot exceed the budget together
+	ledger.mutex.Lock()
+	defer ledger.mutex.Unlock()
+	usage, err := ledger.store.Get(tenant, period)
+	if err != nil {
+		return err
+	}
+	budget := ledger.budgetOf(tenant)
+	if used := usage.Total(); budget > 0 && exceedsBudget(used, characters, budget) {
+		return &TenantBudgetExceededErr{Tenant: tenant, Period: period, Budget: budget, Used: used,
+			Requested: characters}
+	}
+	if characters == 0 {
+		return nil
+	}
+	_, err = ledger.store.Add(tenant, period, TenantUsage{Characters: characters})
+	return err
+}
+
+// release refunds the characters reserved for a failed request of the tenant of the context.
+func (ledger *TenantLedger) release(ctx context.Context, characters int64) error {
+	tenant, ok := TenantFromContext(ctx)
+	if ledger == nil || !ok || characters == 0 {
+		return nil
+	}
+	_, err := ledger.store.Add(tenant, ledger.period(), TenantUsage{Characters: -characters})
+	return err
+}
+
+// startDocument counts the uploaded document for the tenant of the context and keeps it as pending document, so its
+// billed characters can be recorded once it is translated. Pending documents exceeding their TTL are removed.
+func (ledger *TenantLedger) startDocument(ctx context.Context, documentId string) error {
+	tenant, ok := TenantFromContext(ctx)
+	if ledger == nil || !ok {
+		return nil
+	}
+	now := ledger.now()
+	if err := ledger.store.ExpirePendingDocuments(now.Add(-ledger.pendingDocumentTTL)); err != nil {
+		return err
+	}
+	period := now.UTC().Format(tenantPeriodLayout)
+	if err := ledger.store.AddPendingDocument(PendingDocument{
+		DocumentId: documentId,
+		Tenant:     tenant,
+		Period:     period,
+		Uploaded:   now,
+	}); err != nil {
+		return err
+	}
+	_, err := ledger.store.Add(tenant, period, TenantUsage{Documents: 1})
+	return err
+}
+
+// recordDocument records the characters billed for the translated document within the period of its upload for the
+// tenant which uploaded it. The characters of each document are only recorded once.
+func (ledger *TenantLedger) recordDocument(status *DocumentTranslationStatusResponse) error {
+	if ledger == nil || status.Status != StatusDone && status.Status != StatusError {
+		return nil
+	}
+	document, ok, err := ledger.store.TakePendingDocument(status.DocumentId)
+	if err != nil || !ok || status.BilledCharacters == 0 {
+		return err
+	}
+	_, err = ledger.store.Add(document.Tenant, document.Period,
+		TenantUsage{BilledCharacters: int64(status.BilledCharacters)})
+	return err
+}
+
+// releaseTenantCharacters refunds the characters of a failed translation to the tenant of the context and logs an
+// error if the ledger could not be updated.
+func (client *Client) releaseTenantCharacters(ctx context.Context, characters int64) {
+	if err := client.tenants.release(ctx, characters); err != nil {
+		client.debug("could not refund characters of tenant", "error", err.Error())
+	}
+}
@@ -0,0 +1,236 @@
+package deeplclient
+
+import (
+	"context"
+	"errors"
+	"github.com/PineiroHosting/deeplgobindings/pkg/deepltest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// newTenantLedger creates a new tenant ledger with the given options, which have to be valid.
+func newTenantLedger(t *testing.T, options TenantLedgerOptions) *TenantLedger {
+	ledger, err := NewTenantLedger(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ledger
+}
+
+// TestTenantContext tests whether the tenant is stored within the context.
+func TestTenantContext(t *testing.T) {
+	if _, ok := TenantFromContext(context.Background()); ok {
+		t.Fatal("expected no tenant")
+	}
+	if _, ok := TenantFromContext(WithTenant(context.Background(), "")); ok {
+		t.Fatal("expected no tenant for empty identifier")
+	}
+	if tenant, ok := TenantFromContext(WithTenant(context.Background(), "acme")); !ok || tenant != "acme" {
+		t.Fatalf("unexpected tenant: %s", tenant)
+	}
+}
+
+// TestTenantLedgerBudget tests whether translations are metered per tenant and rejected if they exceed the budget of
+// their tenant.
+func TestTenantLedgerBudget(t *testing.T) {
+	ledger := newTenantLedger(t, TenantLedgerOptions{
+		DefaultBudget: 10,
+		Budgets:       map[string]int64{"premium": 100},
+	})
+	client, server := newFakeClient(t, WithTenantLedger(ledger))
+	acme := WithTenant(context.Background(), "acme")
+	req := &TranslationRequest{Text: "Hallo", TargetLang: LangENGB}
+	for i := 0; i < 2; i++ {
+		if _, err := client.TranslateWithContext(acme, req); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, err := client.TranslateWithContext(acme, req)
+	var budgetErr *TenantBudgetExceededErr
+	if !errors.As(err, &budgetErr) || !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("expected tenant budget exceeded error, got: %v", err)
+	}
+	if budgetErr.Tenant != "acme" || budgetErr.Used != 10 || budgetErr.Budget != 10 || budgetErr.Requested != 5 {
+		t.Fatalf("unexpected budget error: %+v", budgetErr)
+	}
+	if _, err = client.TranslateWithContext(WithTenant(context.Background(), "premium"), req); err != nil {
+		t.Fatal(err)
+	}
+	// requests without tenant are not metered
+	if _, err = client.Translate(req); err != nil {
+		t.Fatal(err)
+	}
+	// characters of failed requests are refunded
+	server.InjectError(deepltest.ErrorInjection{Path: "translate", StatusCode: 500, Count: 1})
+	if _, err = client.TranslateWithContext(WithTenant(context.Background(), "premium"), req); err == nil {
+		t.Fatal("expected injected error")
+	}
+	entries, err := ledger.Export()
+	if err != nil {
+		t.Fatal(err)
+	}
+	period := time.Now().UTC().Format(tenantPeriodLayout)
+	expected := []TenantUsage{
+		{Tenant: "acme", Period: period, Characters: 10},
+		{Tenant: "premium", Period: period, Characters: 5},
+	}
+	if !reflect.DeepEqual(entries, expected) {
+		t.Fatalf("unexpected ledger entries: %+v", entries)
+	}
+
+	// raising the budget allows further requests
+	ledger.SetBudget("acme", 15)
+	if _, err = client.TranslateWithContext(acme, req); err != nil {
+		t.Fatal(err)
+	}
+	if usage, _ := ledger.Usage("acme"); usage.Characters != 15 {
+		t.Fatalf("unexpected usage: %+v", usage)
+	}
+}
+
+// TestTenantLedgerRequireTenant tests whether requests without tenant are rejected if required.
+func TestTenantLedgerRequireTenant(t *testing.T) {
+	client, _ := newFakeClient(t, WithTenantLedger(newTenantLedger(t, TenantLedgerOptions{RequireTenant: true})))
+	if _, err := client.Translate(&TranslationRequest{Text: "Hallo", TargetLang: LangENGB}); !errors.Is(err,
+		ErrTenantRequired) {
+		t.Fatalf("expected tenant required error, got: %v", err)
+	}
+}
+
+// TestTenantLedgerDocuments tests whether uploaded documents and their billed characters are metered for the tenant
+// which uploaded them.
+func TestTenantLedgerDocuments(t *testing.T) {
+	ledger := newTenantLedger(t, TenantLedgerOptions{DefaultBudget: 12})
+	client, _ := newFakeClient(t, WithTenantLedger(ledger))
+	ctx := WithTenant(context.Background(), "acme")
+	req := &DocumentTranslationStartRequest{
+		TargetLang: LangDE,
+		File:       []byte("Hello world!"),
+		Filename:   "hello.txt",
+	}
+	document, err := client.StartDocumentTranslateWithContext(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the status may be checked without tenant
+	if err = waitForDocumentTranslation(context.Background(), client, document, fastPolling); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = client.CheckDocumentTranslationStatus((*DocumentTranslationStatusRequest)(document)); err != nil {
+		t.Fatal(err)
+	}
+	usage, err := ledger.Usage("acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usage.BilledCharacters != 12 || usage.Documents != 1 || usage.Characters != 0 {
+		t.Fatalf("unexpected usage: %+v", usage)
+	}
+	if _, err = client.StartDocumentTranslateWithContext(ctx, req); !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("expected tenant budget exceeded error, got: %v", err)
+	}
+}
+
+// TestFileLedgerStore tests whether the usage and the pending documents are persisted and loaded again, so billed
+// characters are recorded within the period of the upload even after a restart.
+func TestFileLedgerStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ledger.json")
+	store, err := NewFileLedgerStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ledger := newTenantLedger(t, TenantLedgerOptions{Store: store})
+	ledger.now = func() time.Time {
+		return time.Date(2026, time.January, 31, 23, 0, 0, 0, time.UTC)
+	}
+	ctx := WithTenant(context.Background(), "acme")
+	if err = ledger.reserve(ctx, 42); err != nil {
+		t.Fatal(err)
+	}
+	if err = ledger.startDocument(ctx, "document"); err != nil {
+		t.Fatal(err)
+	}
+
+	// the document is finished in the next period after a restart
+	if store, err = NewFileLedgerStore(path); err != nil {
+		t.Fatal(err)
+	}
+	ledger = newTenantLedger(t, TenantLedgerOptions{Store: store})
+	ledger.now = func() time.Time {
+		return time.Date(2026, time.February, 1, 1, 0, 0, 0, time.UTC)
+	}
+	status := &DocumentTranslationStatusResponse{DocumentId: "document", Status: StatusDone, BilledCharacters: 50000}
+	for i := 0; i < 2; i++ {
+		if err = ledger.recordDocument(status); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if store, err = NewFileLedgerStore(path); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := store.Entries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []TenantUsage{
+		{Tenant: "acme", Period: "2026-01", Characters: 42, BilledCharacters: 50000, Documents: 1},
+	}
+	if !reflect.DeepEqual(entries, expected) {
+		t.Fatalf("unexpected ledger entries: %+v", entries)
+	}
+	if _, ok, err := store.TakePendingDocument("document"); err != nil || ok {
+		t.Fatalf("expected no pending document, got: %v, %v", ok, err)
+	}
+
+	if err = os.WriteFile(path, []byte("invalid"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = NewFileLedgerStore(path); err == nil {
+		t.Fatal("expected error for invalid ledger file")
+	}
+}
+
+// TestTenantLedgerExpirePendingDocuments tests whether documents which are never seen finished are removed once their
+// TTL is exceeded.
+func TestTenantLedgerExpirePendingDocuments(t *testing.T) {
+	store := NewMemoryLedgerStore()
+	ledger := newTenantLedger(t, TenantLedgerOptions{Store: store, PendingDocumentTTL: time.Hour})
+	now := time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC)
+	ledger.now = func() time.Time {
+		return now
+	}
+	ctx := WithTenant(context.Background(), "acme")
+	for _, documentId := range []string{"abandoned", "recent"} {
+		if err := ledger.startDocument(ctx, documentId); err != nil {
+			t.Fatal(err)
+		}
+		now = now.Add(30 * time.Minute)
+	}
+	now = now.Add(15 * time.Minute)
+	if err := ledger.startDocument(ctx, "new"); err != nil {
+		t.Fatal(err)
+	}
+	if len(store.pending) != 2 || store.pending["abandoned"].DocumentId != "" {
+		t.Fatalf("unexpected pending documents: %v", store.pending)
+	}
+}
+
+// TestTenantLedgerInvalidOptions tests whether invalid options are rejected.
+func TestTenantLedgerInvalidOptions(t *testing.T) {
+	if _, err := NewTenantLedger(TenantLedgerOptions{DefaultBudget: -1}); err == nil {
+		t.Fatal("expected error for negative default budget")
+	}
+	if _, err := NewTenantLedger(TenantLedgerOptions{Budgets: map[string]int64{"acme": -1}}); err == nil {
+		t.Fatal("expected error for negative budget")
+	}
+	if _, err := NewTenantLedger(TenantLedgerOptions{PendingDocumentTTL: -time.Hour}); err == nil {
+		t.Fatal("expected error for negative pending document TTL")
+	}
+	if _, err := NewClient("test", WithTenantLedger(nil)); err == nil {
+		t.Fatal("expected error for nil ledger")
+	}
+}
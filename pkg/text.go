@@ -1,13 +1,13 @@
 package deeplclient
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
-	"fmt"
-	"io"
 	"net/http"
 	"net/url"
 	"strings"
+	"unicode/utf8"
 )
 
 const (
@@ -58,31 +58,27 @@ type TranslationRequest struct {
 	GlossaryId ApiLang
 }
 
+// Translation contains the result of a single translated text.
+type Translation struct {
+	// DetectedSourceLanguage contains the ApiLang detected by the DeepL API.
+	DetectedSourceLanguage ApiLang `json:"detected_source_language"`
+	// Text contains the translated text.
+	Text string `json:"text"`
+}
+
 // TranslationResponse represents the data of the json response of the translation API function.
 type TranslationResponse struct {
 	// Translations contains all requested translations and their results.
-	Translations []struct {
-		// DetectedSourceLanguage contains the ApiLang detected by the DeepL API.
-		DetectedSourceLanguage ApiLang `json:"detected_source_language"`
-		// Text contains the translated text.
-		Text string `json:"text"`
-	} `json:"translations"`
+	Translations []Translation `json:"translations"`
 }
 
-// Translate translates the requested text and returns the translated text or an error if something went wrong.
-func (client *Client) Translate(req *TranslationRequest) (resp *TranslationResponse, err error) {
-	// parse url values for HTTP request
-	values := &url.Values{}
-	if len(req.Text) == 0 {
-		return resp, errors.New("'Text' field of translation request cannot be empty")
-	}
-	values.Add("text", req.Text)
+// values parses the options of the translation request into url values for the HTTP request. The texts are not
+// included and the languages have to be validated before.
+func (req *TranslationRequest) values() (values *url.Values) {
+	values = &url.Values{}
 	if req.SourceLang != "" {
 		values.Add("source_lang", req.SourceLang.String())
 	}
-	if len(req.TargetLang) == 0 {
-		return resp, errors.New("'TargetLang' field of translation request cannot be omitted")
-	}
 	values.Add("target_lang", req.TargetLang.String())
 	if len(req.TagHandling) > 0 {
 		values.Add("tag_handling", strings.Join(req.TagHandling, ","))
@@ -107,18 +103,69 @@ func (client *Client) Translate(req *TranslationRequest) (resp *TranslationRespo
 	if len(req.GlossaryId) > 0 {
 		values.Add("glossary_id", string(req.GlossaryId))
 	}
+	return
+}
+
+// Translate translates the requested text and returns the translated text or an error if something went wrong.
+func (client *Client) Translate(req *TranslationRequest) (resp *TranslationResponse, err error) {
+	return client.TranslateWithContext(context.Background(), req)
+}
+
+// TranslateWithContext translates the requested text and returns the translated text or an error if something went
+// wrong. The request is cancelled as soon as the given context is done.
+func (client *Client) TranslateWithContext(ctx context.Context, req *TranslationRequest) (
+	resp *TranslationResponse, err error) {
+	ctx, span := client.startSpan(ctx, "deepl.translate")
+	defer func() {
+		endSpan(span, err)
+	}()
+	span.SetAttribute(AttributeSourceLang, req.SourceLang.String())
+	span.SetAttribute(AttributeTargetLang, req.TargetLang.String())
+	span.SetAttribute(AttributeCharacters, utf8.RuneCountInString(req.Text))
+	if len(req.Text) == 0 {
+		return resp, errors.New("'Text' field of translation request cannot be empty")
+	}
+	if err = client.validateLangs(req.SourceLang, req.TargetLang); err != nil {
+		return
+	}
+	// parse url values for HTTP request
+	values := req.values()
+	values.Add("text", req.Text)
+	if client.splitOversizedTexts && len(values.Encode()) > maxBodySize {
+		values.Del("text")
+		return client.translateSplit(ctx, req, values)
+	}
+	return client.translate(ctx, values)
+}
+
+// translate executes the translate API function with the given url values, which already contain all texts.
+func (client *Client) translate(ctx context.Context, values *url.Values) (resp *TranslationResponse, err error) {
+	var characters int64
+	for _, text := range (*values)["text"] {
+		characters += int64(utf8.RuneCountInString(text))
+	}
+	if err = client.tenants.reserve(ctx, characters); err != nil {
+		return
+	}
+	if err = client.quota.reserve(ctx, characters); err != nil {
+		client.releaseTenantCharacters(ctx, characters)
+		return
+	}
 	var httpResp *http.Response
-	httpResp, err = client.doApiFunction(translateFunctionUri, http.MethodPost, values)
+	// translations using a glossary created by the client are sent with the key which created it
+	ctx = client.keys.resourceContext(ctx, glossaryFunctionUri, values.Get("glossary_id"))
+	httpResp, err = client.doApiFunction(ctx, translateFunctionUri, http.MethodPost, values)
 	if err != nil {
+		client.quota.release(characters)
+		client.releaseTenantCharacters(ctx, characters)
 		return
 	}
-	defer func(Body io.ReadCloser) {
-		err := Body.Close()
-		if err != nil {
-			fmt.Printf("could not close response of translation request%e\n", err)
-		}
-	}(httpResp.Body)
+	defer client.closeBody(httpResp.Body, "translation")
 	resp = &TranslationResponse{}
-	err = json.NewDecoder(httpResp.Body).Decode(resp)
+	if err = json.NewDecoder(httpResp.Body).Decode(resp); err != nil {
+		return nil, err
+	}
+	client.observeCharacters(ApiLang(values.Get("source_lang")), ApiLang(values.Get("target_lang")),
+		(*values)["text"], resp)
 	return
 }
@@ -1,25 +1,21 @@
 package deeplclient
 
 import (
+	"encoding/json"
 	"net/http"
-	"os"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"testing"
 )
 
 // TestTranslation tests the functionality of the Translate API function within the deeplclient.
 func TestTranslation(t *testing.T) {
-	authKey := os.Getenv("DEEPL_TEST_AUTH_KEY")
-	if authKey == "" {
-		t.Fatal("could not find 'DEEPL_TEST_AUTH_KEY' environment variable")
-	}
-	client := &Client{
-		Client:      &http.Client{},
-		AuthKey:     []byte(authKey),
-		EndpointUrl: endpointUrl,
-	}
+	client := newTestClient(t)
 	if resp, err := client.Translate(&TranslationRequest{
 		Text:       "Hallo Welt!",
-		TargetLang: LangEN,
+		TargetLang: LangENUS,
 	}); err != nil {
 		t.Fatal(err)
 	} else {
@@ -29,3 +25,62 @@ func TestTranslation(t *testing.T) {
 		}
 	}
 }
+
+// newEchoTranslationServer creates a test server which "translates" each text by prefixing it with the target
+// language and counts the requests received.
+func newEchoTranslationServer(t *testing.T, requests *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(requests, 1)
+		if err := r.ParseForm(); err != nil {
+			t.Error(err)
+		}
+		resp := &TranslationResponse{}
+		for _, text := range r.PostForm["text"] {
+			resp.Translations = append(resp.Translations, Translation{
+				DetectedSourceLanguage: LangDE,
+				Text:                   r.PostForm.Get("target_lang") + ":" + text,
+			})
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+}
+
+// TestTranslateBatch tests whether batches are split into several requests and the results are aligned to the input.
+func TestTranslateBatch(t *testing.T) {
+	var requests int32
+	server := newEchoTranslationServer(t, &requests)
+	defer server.Close()
+	client := &Client{
+		Client:      server.Client(),
+		AuthKey:     []byte("test"),
+		EndpointUrl: server.URL + "/v2/",
+	}
+	texts := make([]string, 120)
+	for i := range texts {
+		texts[i] = "Text " + strconv.Itoa(i)
+	}
+	// empty texts must not be sent
+	texts[7] = ""
+	// each of these texts fills more than half of a request
+	texts[60] = strings.Repeat("a", maxBodySize/2+1)
+	texts[61] = strings.Repeat("b", maxBodySize/2+1)
+	translations, err := client.TranslateBatch(&TranslationRequest{TargetLang: LangENUS}, texts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(translations) != len(texts) {
+		t.Fatalf("expected %d translations, got %d", len(texts), len(translations))
+	}
+	for i, text := range texts {
+		expected := ""
+		if text != "" {
+			expected = "EN-US:" + text
+		}
+		if translations[i].Text != expected {
+			t.Fatalf("translation %d is not aligned with its input: %q", i, translations[i].Text)
+		}
+	}
+	if requests != 4 {
+		t.Fatalf("expected texts to be packed into 4 requests, got %d", requests)
+	}
+}
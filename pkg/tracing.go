@@ -0,0 +1,124 @@
+package deeplclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// Names of the attributes set on spans. Document keys are never recorded, as they grant access to the documents.
+const (
+	// AttributeEndpoint is the API function of a request, e.g. "translate" or "document/:id".
+	AttributeEndpoint = "deepl.endpoint"
+	// AttributeHTTPMethod is the HTTP method of a request.
+	AttributeHTTPMethod = "http.method"
+	// AttributeHTTPStatusCode is the HTTP status code of the last response of a request.
+	AttributeHTTPStatusCode = "http.status_code"
+	// AttributeRetryCount is the amount of times a request has been sent again.
+	AttributeRetryCount = "deepl.retry_count"
+	// AttributeSourceLang is the source language of a translation.
+	AttributeSourceLang = "deepl.source_lang"
+	// AttributeTargetLang is the target language of a translation.
+	AttributeTargetLang = "deepl.target_lang"
+	// AttributeCharacters is the amount of characters to be translated.
+	AttributeCharacters = "deepl.characters"
+	// AttributeDocumentId is the ID of a translated document.
+	AttributeDocumentId = "deepl.document_id"
+	// AttributeDocumentStatus is the status of a document translation.
+	AttributeDocumentStatus = "deepl.document_status"
+	// AttributeBytes is the amount of bytes of a downloaded document.
+	AttributeBytes = "deepl.bytes"
+	// AttributeAuthKeyId is the ID of the pooled auth key which sent a request (see AuthKeyId).
+	AttributeAuthKeyId = "deepl.auth_key_id"
+)
+
+// Tracer starts spans for the operations of a client. Each operation (e.g. a translation or a step of the document
+// lifecycle) gets its own span, which contains a child span for the HTTP request including all retries.
+//
+// The interface does not depend on any tracing library, so this package stays free of dependencies. The separate module
+// github.com/PineiroHosting/deeplgobindings/otel provides an adapter for OpenTelemetry.
+type Tracer interface {
+	// Start starts a new span as child of the span contained in the context, if any, and returns a context containing
+	// the new span.
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span represents a single traced operation.
+type Span interface {
+	// SetAttribute sets the attribute of the span (see the Attribute constants).
+	SetAttribute(key string, value interface{})
+	// RecordError records the error which caused the operation to fail.
+	RecordError(err error)
+	// End finishes the span.
+	End()
+}
+
+// TracePropagator may be implemented by a Tracer in order to propagate the trace context to the API server (or any
+// proxy in between). It is called for every attempt of a request, e.g. to add the W3C traceparent header.
+type TracePropagator interface {
+	// Inject adds the trace context of the span contained in the context to the header.
+	Inject(ctx context.Context, header http.Header)
+}
+
+// WithTracer sets the tracer which starts spans for the operations of the client.
+func WithTracer(tracer Tracer) Option {
+	return func(client *Client) error {
+		if tracer == nil {
+			return errors.New("tracer cannot be nil")
+		}
+		client.tracer = tracer
+		return nil
+	}
+}
+
+// noopSpan is used if no tracer is configured.
+type noopSpan struct{}
+
+// SetAttribute does nothing.
+func (noopSpan) SetAttribute(string, interface{}) {}
+
+// RecordError does nothing.
+func (noopSpan) RecordError(error) {}
+
+// End does nothing.
+func (noopSpan) End() {}
+
+// startSpan starts a new span if a tracer is configured.
+func (client *Client) startSpan(ctx context.Context, name string) (context.Context, Span) {
+	if client.tracer == nil {
+		return ctx, noopSpan{}
+	}
+	return client.tracer.Start(ctx, name)
+}
+
+// injectTraceContext propagates the trace context into the header if the tracer supports it.
+func (client *Client) injectTraceContext(ctx context.Context, header http.Header) {
+	if propagator, ok := client.tracer.(TracePropagator); ok {
+		propagator.Inject(ctx, header)
+	}
+}
+
+// endSpan records the error, if any, and finishes the span.
+func endSpan(span Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+	}
+	span.End()
+}
+
+// startDocumentSpan starts the span of a document upload.
+func (client *Client) startDocumentSpan(ctx context.Context, req *DocumentTranslationStartRequest) (context.Context,
+	Span) {
+	ctx, span := client.startSpan(ctx, "deepl.start_document_translate")
+	span.SetAttribute(AttributeSourceLang, req.SourceLang.String())
+	span.SetAttribute(AttributeTargetLang, req.TargetLang.String())
+	return ctx, span
+}
+
+// endDocumentSpan records the ID of the uploaded document and finishes the span of the document upload.
+func endDocumentSpan(span Span, resp *DocumentTranslationStartResponse, err error) {
+	if resp != nil {
+		span.SetAttribute(AttributeDocumentId, resp.DocumentId)
+	}
+	endSpan(span, err)
+}
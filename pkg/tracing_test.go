@@ -0,0 +1,162 @@
+package deeplclient
+
+import (
+	"context"
+	"fmt"
+	"github.com/PineiroHosting/deeplgobindings/pkg/deepltest"
+	"net/http"
+	"sync"
+	"testing"
+)
+
+// testSpan records the attributes and errors of a span.
+type testSpan struct {
+	name       string
+	parent     *testSpan
+	attributes map[string]interface{}
+	errors     []error
+	ended      bool
+}
+
+// SetAttribute records the attribute.
+func (span *testSpan) SetAttribute(key string, value interface{}) {
+	span.attributes[key] = value
+}
+
+// RecordError records the error.
+func (span *testSpan) RecordError(err error) {
+	span.errors = append(span.errors, err)
+}
+
+// End marks the span as ended.
+func (span *testSpan) End() {
+	span.ended = true
+}
+
+// testSpanKey is the context key of the current test span.
+type testSpanKey struct{}
+
+// testTracer records all spans and propagates the name of the current span.
+type testTracer struct {
+	mutex sync.Mutex
+	spans []*testSpan
+}
+
+// Start starts a new span as child of the span in the context.
+func (tracer *testTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	parent, _ := ctx.Value(testSpanKey{}).(*testSpan)
+	span := &testSpan{name: name, parent: parent, attributes: map[string]interface{}{}}
+	tracer.mutex.Lock()
+	defer tracer.mutex.Unlock()
+	tracer.spans = append(tracer.spans, span)
+	return context.WithValue(ctx, testSpanKey{}, span), span
+}
+
+// Inject adds the name of the current span to the header.
+func (tracer *testTracer) Inject(ctx context.Context, header http.Header) {
+	if span, ok := ctx.Value(testSpanKey{}).(*testSpan); ok {
+		header.Set("X-Test-Span", span.name)
+	}
+}
+
+// find returns all spans with the given name.
+func (tracer *testTracer) find(name string) (spans []*testSpan) {
+	for _, span := range tracer.spans {
+		if span.name == name {
+			spans = append(spans, span)
+		}
+	}
+	return
+}
+
+// TestTracing tests whether operations produce spans with the expected attributes and propagate the trace context.
+func TestTracing(t *testing.T) {
+	server := deepltest.NewServer()
+	defer server.Close()
+	server.InjectError(deepltest.ErrorInjection{Path: "translate", StatusCode: http.StatusServiceUnavailable, Count: 1})
+	tracer := &testTracer{}
+	client, err := NewClient("test", WithEndpoint(server.URL), WithTracer(tracer),
+		WithRetryPolicy(&RetryPolicy{MaxAttempts: 2}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = client.Translate(&TranslationRequest{Text: "Hallo", SourceLang: LangDE, TargetLang: LangENGB}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = client.TranslateDocument(&DocumentTranslationStartRequest{
+		TargetLang: LangDE,
+		File:       []byte("Hello world!"),
+		Filename:   "hello.txt",
+	}, fastPolling); err != nil {
+		t.Fatal(err)
+	}
+
+	translateSpans := tracer.find("deepl.translate")
+	if len(translateSpans) != 1 {
+		t.Fatalf("expected one translate span, got %d", len(translateSpans))
+	}
+	translateSpan := translateSpans[0]
+	if translateSpan.attributes[AttributeSourceLang] != "DE" || translateSpan.attributes[AttributeTargetLang] != "EN-GB" ||
+		translateSpan.attributes[AttributeCharacters] != 5 {
+		t.Fatalf("unexpected attributes of translate span: %v", translateSpan.attributes)
+	}
+	httpSpan := tracer.find("deepl.http_request")[0]
+	if httpSpan.parent != translateSpan || httpSpan.attributes[AttributeRetryCount] != 1 ||
+		httpSpan.attributes[AttributeHTTPStatusCode] != http.StatusOK ||
+		httpSpan.attributes[AttributeEndpoint] != "translate" {
+		t.Fatalf("unexpected HTTP span: %+v", httpSpan)
+	}
+
+	documentSpan := tracer.find("deepl.translate_document")[0]
+	documentId := tracer.find("deepl.start_document_translate")[0].attributes[AttributeDocumentId]
+	if documentId == nil || documentId == "" {
+		t.Fatal("document ID is missing")
+	}
+	statusSpans := tracer.find("deepl.check_document_translation_status")
+	if len(statusSpans) < 1 || statusSpans[len(statusSpans)-1].attributes[AttributeDocumentStatus] != string(StatusDone) {
+		t.Fatalf("unexpected status spans: %+v", statusSpans)
+	}
+	downloadSpan := tracer.find("deepl.download_translated_document")[0]
+	if downloadSpan.parent != documentSpan || downloadSpan.attributes[AttributeBytes] != int64(len("[DE] Hello world!")) {
+		t.Fatalf("unexpected download span: %+v", downloadSpan)
+	}
+	var documentKey string
+	for _, req := range server.Requests() {
+		if req.Header.Get("X-Test-Span") != "deepl.http_request" {
+			t.Fatalf("trace context has not been propagated: %v", req.Header)
+		}
+		if key := req.Form.Get("document_key"); key != "" {
+			documentKey = key
+		}
+	}
+	for _, span := range tracer.spans {
+		if !span.ended {
+			t.Fatalf("span %s has not been ended", span.name)
+		}
+		for key, value := range span.attributes {
+			// the document key must never be recorded
+			if fmt.Sprint(value) == documentKey {
+				t.Fatalf("attribute %s of span %s contains the document key", key, span.name)
+			}
+		}
+	}
+}
+
+// TestTracingError tests whether errors are recorded on the spans.
+func TestTracingError(t *testing.T) {
+	server := deepltest.NewServer()
+	defer server.Close()
+	server.InjectError(deepltest.ErrorInjection{Path: "usage", StatusCode: StatusQuotaExceeded, Count: 1})
+	tracer := &testTracer{}
+	client, err := NewClient("test", WithEndpoint(server.URL), WithTracer(tracer))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = client.GetUsage(); err == nil {
+		t.Fatal("expected error")
+	}
+	usageSpan := tracer.find("deepl.get_usage")[0]
+	if len(usageSpan.errors) != 1 || len(tracer.find("deepl.http_request")[0].errors) != 1 {
+		t.Fatalf("errors have not been recorded: %+v", tracer.spans)
+	}
+}
@@ -0,0 +1,53 @@
+package deeplclient
+
+import (
+	"context"
+)
+
+// TextTranslator translates texts. It is implemented by *Client and all decorators shipped with this package, so
+// they can be stacked.
+type TextTranslator interface {
+	// TranslateWithContext translates the requested text and returns the translated text or an error if something
+	// went wrong.
+	TranslateWithContext(ctx context.Context, req *TranslationRequest) (*TranslationResponse, error)
+}
+
+// DocumentTranslator translates documents. It is implemented by *Client and all decorators shipped with this package,
+// so they can be stacked.
+type DocumentTranslator interface {
+	// StartDocumentTranslateWithContext starts the translation process of a given document and returns document
+	// information or an error if something went wrong.
+	StartDocumentTranslateWithContext(ctx context.Context, req *DocumentTranslationStartRequest) (
+		*DocumentTranslationStartResponse, error)
+	// CheckDocumentTranslationStatusWithContext returns the current status of the document translation or an error,
+	// if something went wrong.
+	CheckDocumentTranslationStatusWithContext(ctx context.Context, req *DocumentTranslationStatusRequest) (
+		*DocumentTranslationStatusResponse, error)
+	// DownloadTranslatedDocumentWithContext returns the translated document or an error, if something went wrong.
+	DownloadTranslatedDocumentWithContext(ctx context.Context, req *DocumentTranslationDownloadRequest) ([]byte,
+		error)
+}
+
+// UsageReporter reports the usage of the current billing period. It is implemented by *Client and all decorators
+// shipped with this package, so they can be stacked.
+type UsageReporter interface {
+	// GetUsageWithContext returns the usage information for the current billing period.
+	GetUsageWithContext(ctx context.Context) (*UsageResponse, error)
+}
+
+// Translator combines all interfaces implemented by *Client and the decorators shipped with this package.
+type Translator interface {
+	TextTranslator
+	DocumentTranslator
+	UsageReporter
+}
+
+// translatorSet combines decorators of the single interfaces into a Translator.
+type translatorSet struct {
+	TextTranslator
+	DocumentTranslator
+	UsageReporter
+}
+
+// make sure the client can be used wherever a translator is required
+var _ Translator = (*Client)(nil)
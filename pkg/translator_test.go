@@ -0,0 +1,90 @@
+package deeplclient
+
+import (
+	"context"
+	"github.com/PineiroHosting/deeplgobindings/pkg/deepltest"
+	"net/http"
+	"testing"
+)
+
+// TestStackedTranslators tests whether decorators can be stacked on top of the client.
+func TestStackedTranslators(t *testing.T) {
+	client, server := newFakeClient(t)
+	server.InjectError(deepltest.ErrorInjection{
+		Path:       "translate",
+		StatusCode: http.StatusTooManyRequests,
+		Count:      2,
+	})
+	limiter := NewRateLimiter(1000, 0, 0)
+	var translator Translator = WithThrottling(WithRetries(client, &RetryPolicy{MaxAttempts: 3}), limiter)
+	resp, err := translator.TranslateWithContext(context.Background(), &TranslationRequest{
+		Text:       "Hallo Welt!",
+		TargetLang: LangENGB,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Translations) != 1 || resp.Translations[0].Text != "[EN-GB] Hallo Welt!" {
+		t.Fatalf("unexpected translation response: %+v", resp)
+	}
+	if len(server.Requests()) != 3 {
+		t.Fatalf("expected 3 requests, got %d", len(server.Requests()))
+	}
+
+	server.InjectError(deepltest.ErrorInjection{Path: "document/", StatusCode: http.StatusServiceUnavailable, Count: 1})
+	result, err := TranslateDocumentWithTranslator(context.Background(), translator, &DocumentTranslationStartRequest{
+		TargetLang: LangDE,
+		File:       []byte("Hello world!"),
+		Filename:   "hello.txt",
+	}, fastPolling)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(result) != "[DE] Hello world!" {
+		t.Fatalf("unexpected translated document: %q", result)
+	}
+}
+
+// flakyTextTranslator is a text-only translator which fails with too many requests a given amount of times.
+type flakyTextTranslator struct {
+	failures int
+	calls    int
+}
+
+// TranslateWithContext fails until all failures have been returned and echoes the text afterwards.
+func (translator *flakyTextTranslator) TranslateWithContext(_ context.Context, req *TranslationRequest) (
+	*TranslationResponse, error) {
+	translator.calls++
+	if translator.calls <= translator.failures {
+		return nil, &TooManyRequestsErr{&APIError{StatusCode: http.StatusTooManyRequests}}
+	}
+	return &TranslationResponse{Translations: []Translation{{Text: req.Text}}}, nil
+}
+
+// TestNarrowDecorators tests whether translators implementing only a part of the Translator interface can be
+// decorated.
+func TestNarrowDecorators(t *testing.T) {
+	flaky := &flakyTextTranslator{failures: 2}
+	translator := WithTextThrottling(WithTextRetries(flaky, &RetryPolicy{MaxAttempts: 3}), NewRateLimiter(1000, 0, 0))
+	resp, err := translator.TranslateWithContext(context.Background(), &TranslationRequest{Text: "Hallo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if flaky.calls != 3 || resp.Translations[0].Text != "Hallo" {
+		t.Fatalf("unexpected result after %d calls: %+v", flaky.calls, resp)
+	}
+
+	client, _ := newFakeClient(t)
+	reporter := WithUsageThrottling(WithUsageRetries(client, &RetryPolicy{MaxAttempts: 2}), NewRateLimiter(1000, 0, 0))
+	if _, err = reporter.GetUsageWithContext(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	var documents DocumentTranslator = WithDocumentThrottling(WithDocumentRetries(client, nil), nil)
+	if _, err = TranslateDocumentWithTranslator(context.Background(), documents, &DocumentTranslationStartRequest{
+		TargetLang: LangDE,
+		File:       []byte("Hello world!"),
+		Filename:   "hello.txt",
+	}, fastPolling); err != nil {
+		t.Fatal(err)
+	}
+}